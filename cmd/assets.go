@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/tools/setup-envtest/versions"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/envtest"
+)
+
+type assetsFetchOptions struct {
+	envtestArch    string
+	assetsDir      string
+	verifyChecksum bool
+}
+
+// NewAssetsCommand groups commands for managing envtest assets.
+func NewAssetsCommand(out output.Output) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "assets",
+		Short: "Manages envtest assets (kube-apiserver and etcd binaries)",
+	}
+	cmd.AddCommand(newAssetsFetchCommand(out))
+	return cmd
+}
+
+func newAssetsFetchCommand(out output.Output) *cobra.Command {
+	options := &assetsFetchOptions{
+		envtestArch: runtime.GOARCH,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "fetch VERSION...",
+		Short: "Downloads envtest assets for the provided k8s versions",
+		Long: "Downloads envtest assets for the provided k8s versions, e.g. 1.30 or 1.30.2, to the assets " +
+			"directory. The directory can be copied to an air-gapped machine and used with " +
+			"\"serve --assets-dir DIR --no-download\".",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAssetsFetch(args, options, out)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&options.envtestArch, "envtest-arch", options.envtestArch,
+		"arch value for k8s server assets",
+	)
+
+	cmd.Flags().StringVar(
+		&options.assetsDir, "assets-dir", options.assetsDir,
+		"directory of envtest assets store, defaults to setup-envtest store directory",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.verifyChecksum, "verify-checksums", options.verifyChecksum,
+		"verify checksums of downloaded envtest assets",
+	)
+
+	return cmd
+}
+
+func runAssetsFetch(args []string, o *assetsFetchOptions, out output.Output) error {
+	selectors := make([]versions.Selector, 0, len(args))
+	for _, arg := range args {
+		spec, err := versions.FromExpr(strings.TrimPrefix(arg, "v"))
+		if err != nil {
+			return fmt.Errorf("invalid k8s version %q: %w", arg, err)
+		}
+		selectors = append(selectors, spec.Selector)
+	}
+
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer done()
+
+	opts := []envtest.Option{envtest.Arch(o.envtestArch)}
+	if o.assetsDir != "" {
+		opts = append(opts, envtest.AssetsDir(o.assetsDir))
+	}
+	if o.verifyChecksum {
+		opts = append(opts, envtest.VerifyChecksum())
+	}
+
+	for i, selector := range selectors {
+		out.StartOperation(fmt.Sprintf("Fetching assets for k8s version %s", args[i]))
+		testEnv, err := envtest.PrepareVersion(ctx, selector, opts...)
+		out.EndOperation(err == nil)
+		if err != nil {
+			return err
+		}
+		if testEnv.K8sVersion().String() != selector.String() {
+			out.Warnf("Envtest assets for k8s version %s are not available, fetched nearest version %s",
+				args[i], testEnv.K8sVersion())
+		}
+		out.Infof("Assets for k8s version %s are in %s", testEnv.K8sVersion(), testEnv.BinaryAssetsDirectory)
+	}
+	return nil
+}
+
+// addEnvtestAssetsFlags adds flags selecting k8s version and envtest assets
+// used for starting the API server.
+func addEnvtestAssetsFlags(cmd *cobra.Command, options *serveOptions) {
+	cmd.Flags().StringVar(
+		&options.kubernetesVersion, "kubernetes-version", options.kubernetesVersion,
+		"k8s version of the API server, e.g. 1.30, instead of the version detected from the bundle",
+	)
+
+	cmd.Flags().StringVar(
+		&options.assetsDir, "assets-dir", options.assetsDir,
+		"directory of envtest assets store, defaults to setup-envtest store directory",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.noDownload, "no-download", options.noDownload,
+		"don't download envtest assets, only assets present in the assets directory are used",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.verifyChecksum, "verify-checksums", options.verifyChecksum,
+		"verify checksums of downloaded envtest assets",
+	)
+
+	cmd.Flags().StringVar(
+		&options.kubeAPIServerBinary, "kube-apiserver-binary", options.kubeAPIServerBinary,
+		"path to kube-apiserver binary used instead of envtest assets",
+	)
+
+	cmd.Flags().StringVar(
+		&options.etcdBinary, "etcd-binary", options.etcdBinary,
+		"path to etcd binary used instead of envtest assets",
+	)
+}
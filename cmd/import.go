@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/importer"
+)
+
+type importOptions struct {
+	kubeconfigPath    string
+	context           string
+	namespacePrefix   string
+	namespaceMap      map[string]string
+	skipClusterScoped bool
+	mode              string
+	rewriterRulesPath string
+	reportPath        string
+	filters           importFilterOptions
+}
+
+// importFilterOptions select bundle objects that are imported.
+type importFilterOptions struct {
+	includeNamespaces []string
+	excludeNamespaces []string
+	includeKinds      []string
+	excludeKinds      []string
+	selector          string
+}
+
+// NewImportCommand imports the provided bundle to an existing cluster.
+func NewImportCommand(out output.Output) *cobra.Command {
+	options := &importOptions{
+		mode: string(importer.ModeCreate),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "import SUPPORT_BUNDLE_PATH",
+		Short: "Imports bundle resources to an existing Kubernetes cluster",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runImport(args[0], options, out)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&options.kubeconfigPath, "kubeconfig", options.kubeconfigPath,
+		"path to the kubeconfig of the target cluster, defaults to KUBECONFIG env variable or ~/.kube/config",
+	)
+
+	cmd.Flags().StringVar(
+		&options.context, "context", options.context,
+		"kubeconfig context of the target cluster, defaults to the current context",
+	)
+
+	cmd.Flags().StringVar(
+		&options.namespacePrefix, "namespace-prefix", options.namespacePrefix,
+		"prefix added to namespaces of imported objects, namespaces mapped with --namespace-map are not prefixed",
+	)
+
+	cmd.Flags().StringToStringVar(
+		&options.namespaceMap, "namespace-map", options.namespaceMap,
+		"import objects from bundle namespace to a target namespace, e.g. kube-system=sandbox",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.skipClusterScoped, "skip-cluster-scoped", options.skipClusterScoped,
+		"skip cluster-scoped resources including CRDs, namespaces are still created",
+	)
+
+	cmd.Flags().StringVar(
+		&options.mode, "mode", options.mode,
+		fmt.Sprintf(
+			"how objects are written to the cluster, one of: %s (existing objects are skipped), %s (server-side apply)",
+			importer.ModeCreate, importer.ModeApply,
+		),
+	)
+
+	cmd.Flags().StringVar(
+		&options.rewriterRulesPath, "rewriter-rules", options.rewriterRulesPath,
+		"path to YAML file with additional rewriter rules applied to imported objects",
+	)
+
+	addImportFilterFlags(cmd, &options.filters)
+
+	cmd.Flags().StringVar(
+		&options.reportPath, "report", options.reportPath,
+		"write report with outcome of every bundle object to the file, YAML for .yaml or .yml extension, JSON otherwise",
+	)
+
+	return cmd
+}
+
+func runImport(bundlePath string, o *importOptions, out output.Output) error {
+	mode := importer.Mode(o.mode)
+	if mode != importer.ModeCreate && mode != importer.ModeApply {
+		return fmt.Errorf("unsupported mode %q, must be one of %q or %q", o.mode, importer.ModeCreate, importer.ModeApply)
+	}
+
+	rr, err := resourceRewriter(o.rewriterRulesPath)
+	if err != nil {
+		return err
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = o.kubeconfigPath
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules,
+		&clientcmd.ConfigOverrides{CurrentContext: o.context},
+	)
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	supportBundle, err := bundle.New(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to get bundle from path %q: %w", bundlePath, err)
+	}
+
+	filterOptions, err := o.filters.importerOptions()
+	if err != nil {
+		return err
+	}
+
+	importOptions := []importer.Option{
+		importer.WithRewriter(rr),
+		importer.WithMode(mode),
+		importer.WithRemoveMissingOwnerReferences(),
+	}
+	importOptions = append(importOptions, filterOptions...)
+	if o.namespacePrefix != "" {
+		importOptions = append(importOptions, importer.WithNamespacePrefix(o.namespacePrefix))
+	}
+	if len(o.namespaceMap) > 0 {
+		importOptions = append(importOptions, importer.WithNamespaceMap(o.namespaceMap))
+	}
+	if o.skipClusterScoped {
+		importOptions = append(importOptions, importer.WithSkipClusterScoped())
+	}
+
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer done()
+
+	report := &importer.Report{}
+	importOptions = append(importOptions, importer.WithReport(report))
+
+	out.StartOperation(fmt.Sprintf("Importing bundle resources to %s", restConfig.Host))
+	err = importer.ImportBundle(ctx, supportBundle, restConfig, out, importOptions...)
+	out.EndOperation(err == nil)
+	printImportSummary(out, "Import summary:", report)
+	if o.reportPath != "" {
+		if writeErr := report.WriteFile(o.reportPath); writeErr != nil {
+			out.Error(writeErr, "failed to write import report")
+		} else {
+			out.Infof("Import report was written to %s", o.reportPath)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to import support bundle resources: %w", err)
+	}
+	return nil
+}
+
+// addImportFilterFlags adds flags selecting bundle objects that are imported.
+func addImportFilterFlags(cmd *cobra.Command, options *importFilterOptions) {
+	cmd.Flags().StringSliceVar(
+		&options.includeNamespaces, "include-namespaces", options.includeNamespaces,
+		"import only objects from the namespaces, cluster-scoped objects are imported only when referenced by "+
+			"the imported objects (nodes of pods, volumes and storage classes of claims, CRDs)",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&options.excludeNamespaces, "exclude-namespaces", options.excludeNamespaces,
+		"don't import objects from the namespaces",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&options.includeKinds, "include-kinds", options.includeKinds,
+		"import only objects of the kinds in Kind, Kind.group or Kind.version.group format, e.g. Deployment.apps",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&options.excludeKinds, "exclude-kinds", options.excludeKinds,
+		"don't import objects of the kinds in Kind, Kind.group or Kind.version.group format, e.g. Event",
+	)
+
+	cmd.Flags().StringVarP(
+		&options.selector, "selector", "l", options.selector,
+		"import only objects matching the label selector, e.g. app=web,tier!=cache",
+	)
+}
+
+// importerOptions returns import options of the filters.
+func (o importFilterOptions) importerOptions() ([]importer.Option, error) {
+	var opts []importer.Option
+	if len(o.includeNamespaces) > 0 {
+		opts = append(opts, importer.WithIncludeNamespaces(o.includeNamespaces...))
+	}
+	if len(o.excludeNamespaces) > 0 {
+		opts = append(opts, importer.WithExcludeNamespaces(o.excludeNamespaces...))
+	}
+	if len(o.includeKinds) > 0 {
+		opts = append(opts, importer.WithIncludeKinds(parseKinds(o.includeKinds)...))
+	}
+	if len(o.excludeKinds) > 0 {
+		opts = append(opts, importer.WithExcludeKinds(parseKinds(o.excludeKinds)...))
+	}
+	if o.selector != "" {
+		selector, err := labels.Parse(o.selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %w", o.selector, err)
+		}
+		opts = append(opts, importer.WithLabelSelector(selector))
+	}
+	return opts, nil
+}
+
+// key identifies the filters, it is empty when no filter is set.
+func (o importFilterOptions) key() string {
+	if len(o.includeNamespaces)+len(o.excludeNamespaces)+len(o.includeKinds)+len(o.excludeKinds) == 0 &&
+		o.selector == "" {
+		return ""
+	}
+	return fmt.Sprintf("namespaces=%v,-%v;kinds=%v,-%v;selector=%s",
+		o.includeNamespaces, o.excludeNamespaces, o.includeKinds, o.excludeKinds, o.selector)
+}
+
+func parseKinds(kinds []string) []schema.GroupVersionKind {
+	gvks := make([]schema.GroupVersionKind, 0, len(kinds))
+	for _, kind := range kinds {
+		gvks = append(gvks, importer.ParseKind(kind))
+	}
+	return gvks
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/importer"
+)
+
+type inspectOptions struct {
+	outputFormat string
+}
+
+// NewInspectCommand prints inventory of the bundle without starting an API
+// server.
+func NewInspectCommand(out output.Output) *cobra.Command {
+	options := &inspectOptions{
+		outputFormat: "json",
+	}
+
+	cmd := &cobra.Command{
+		Use:   "inspect SUPPORT_BUNDLE_PATH",
+		Short: "Prints inventory of the bundle resources without importing them",
+		Long: "Loads the bundle files the same way as the import and prints the number of objects of each " +
+			"kind, namespaces, CRDs, log files, files with errors recorded during the bundle collection and " +
+			"files that would fail to be imported.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInspect(args[0], options, out)
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&options.outputFormat, "output", "o", options.outputFormat,
+		"output format, one of: json, yaml",
+	)
+
+	return cmd
+}
+
+func runInspect(bundlePath string, o *inspectOptions, out output.Output) error {
+	if o.outputFormat != "json" && o.outputFormat != "yaml" {
+		return fmt.Errorf("unsupported output format %q, must be one of: json, yaml", o.outputFormat)
+	}
+
+	supportBundle, err := bundle.New(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to get bundle from path %q: %w", bundlePath, err)
+	}
+
+	inventory, err := importer.Inspect(supportBundle)
+	if err != nil {
+		return fmt.Errorf("failed to inspect bundle: %w", err)
+	}
+
+	var data []byte
+	if o.outputFormat == "yaml" {
+		data, err = yaml.Marshal(inventory)
+	} else {
+		data, err = json.MarshalIndent(inventory, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle inventory: %w", err)
+	}
+	out.Result(string(data))
+	return nil
+}
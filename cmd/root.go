@@ -23,6 +23,10 @@ func NewCommand(in io.Reader, out, errOut io.Writer) (*cobra.Command, output.Out
 	})))
 
 	rootCmd.AddCommand(NewServeCommand(rootOpts.Output))
+	rootCmd.AddCommand(NewImportCommand(rootOpts.Output))
+	rootCmd.AddCommand(NewInspectCommand(rootOpts.Output))
+	rootCmd.AddCommand(NewSnapshotCommand(rootOpts.Output))
+	rootCmd.AddCommand(NewAssetsCommand(rootOpts.Output))
 
 	return rootCmd, rootOpts.Output
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"os/signal"
 	"runtime"
 	"syscall"
@@ -14,11 +15,11 @@ import (
 
 	"github.com/mesosphere/dkp-cli-runtime/core/output"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
-	"github.com/mhrabovcin/troubleshoot-live/pkg/envtest"
-	"github.com/mhrabovcin/troubleshoot-live/pkg/importer"
 	"github.com/mhrabovcin/troubleshoot-live/pkg/kubernetes"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/manager"
 	"github.com/mhrabovcin/troubleshoot-live/pkg/proxy"
 	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
 )
@@ -27,27 +28,58 @@ type serveOptions struct {
 	kubeconfigPath        string
 	proxyAddress          string
 	envtestArch           string
+	kubernetesVersion     string
+	assetsDir             string
+	noDownload            bool
+	verifyChecksum        bool
+	kubeAPIServerBinary   string
+	etcdBinary            string
 	serviceClusterIPRange string
 	serviceNodePortRange  string
+	logsReplaySpeed       float64
+	anchorClock           bool
+	rewriterRulesPath     string
+	readOnly              bool
+	readOnlyAllow         []string
+	tls                   bool
+	tlsCertFile           string
+	tlsKeyFile            string
+	tlsCAFile             string
+	tlsSANs               []string
+	auth                  string
+	authToken             string
+	clientCAFile          string
+	managementAPI         bool
+	managementUploadDir   string
+	managementMaxUpload   int64
+	dataDir               string
+	reimport              bool
+	storageBackendType    string
+	snapshots             []string
+	importFilters         importFilterOptions
 }
 
-const internalProxyHTTPPrefix = "/bundles/default"
-const defaultStorageID = "default"
-
-// NewServeCommand serves the provided bundle.
+// NewServeCommand serves the provided bundles.
 func NewServeCommand(out output.Output) *cobra.Command {
 	options := &serveOptions{
-		kubeconfigPath: "./support-bundle-kubeconfig",
-		proxyAddress:   "localhost:8080",
-		envtestArch:    runtime.GOARCH,
+		kubeconfigPath:      "./support-bundle-kubeconfig",
+		proxyAddress:        "localhost:8080",
+		envtestArch:         runtime.GOARCH,
+		storageBackendType:  storageBackendEtcd,
+		managementMaxUpload: manager.DefaultMaxUploadSize,
 	}
 
 	cmd := &cobra.Command{
-		Use:   "serve SUPPORT_BUNDLE_PATH",
+		Use:   "serve [ID=]SUPPORT_BUNDLE_PATH...",
 		Short: "Starts a local envtest based Kubernetes API server with bundle resources",
-		Args:  cobra.ExactArgs(1),
+		Long: "Starts a local envtest based Kubernetes API server for each of the provided bundles. " +
+			"Bundles are served under /bundles/ID prefix of the proxy and the kubeconfig contains " +
+			"a context for each bundle. The ID is derived from the bundle file name unless it is " +
+			"provided explicitly, a single bundle is served as \"default\". With --management-api " +
+			"bundles can be uploaded, listed and deleted at runtime and no bundle has to be provided.",
+		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runServe(args[0], options, out)
+			return runServe(args, options, out)
 		},
 	}
 
@@ -67,6 +99,8 @@ func NewServeCommand(out output.Output) *cobra.Command {
 		"arch value for k8s server assets",
 	)
 
+	addEnvtestAssetsFlags(cmd, options)
+
 	cmd.Flags().StringVar(
 		&options.serviceClusterIPRange, "service-cluster-ip-range", options.serviceClusterIPRange,
 		"override k8s api server service ClusterIP range. Mask must be >= /12 range.",
@@ -77,64 +111,236 @@ func NewServeCommand(out output.Output) *cobra.Command {
 		"override k8s api server service node port range",
 	)
 
+	cmd.Flags().Float64Var(
+		&options.logsReplaySpeed, "logs-replay-speed", options.logsReplaySpeed,
+		"replay followed logs paced by their timestamps with given speed multiplier (e.g. 1 or 10), 0 disables replay",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.anchorClock, "anchor-clock", options.anchorClock,
+		"shift served timestamps so that relative ages match the time when the bundle was collected",
+	)
+
+	cmd.Flags().StringVar(
+		&options.rewriterRulesPath, "rewriter-rules", options.rewriterRulesPath,
+		"path to YAML file with additional rewriter rules applied to imported and served objects",
+	)
+
+	addImportFilterFlags(cmd, &options.importFilters)
+
+	cmd.Flags().BoolVar(
+		&options.readOnly, "read-only", options.readOnly,
+		"reject requests that would modify resources imported from the bundle",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&options.readOnlyAllow, "read-only-allow", options.readOnlyAllow,
+		"additional resources in resource.group format that can be created in read-only mode",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.tls, "tls", options.tls,
+		"serve proxy over TLS with certificates generated for the session",
+	)
+
+	cmd.Flags().StringVar(
+		&options.tlsCertFile, "tls-cert-file", options.tlsCertFile,
+		"serve proxy over TLS with the provided certificate file, requires --tls-key-file",
+	)
+
+	cmd.Flags().StringVar(
+		&options.tlsKeyFile, "tls-key-file", options.tlsKeyFile,
+		"key file for the certificate provided with --tls-cert-file",
+	)
+
+	cmd.Flags().StringVar(
+		&options.tlsCAFile, "tls-ca-file", options.tlsCAFile,
+		"CA file embedded in the kubeconfig when certificate is provided with --tls-cert-file",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&options.tlsSANs, "tls-san", options.tlsSANs,
+		"additional DNS names or IP addresses of the generated serving certificate",
+	)
+
+	cmd.Flags().StringVar(
+		&options.auth, "auth", options.auth,
+		"require authentication of proxy requests, one of: token, client-cert",
+	)
+
+	cmd.Flags().StringVar(
+		&options.authToken, "auth-token", options.authToken,
+		"static bearer token for token authentication, generated if not provided",
+	)
+
+	cmd.Flags().StringVar(
+		&options.clientCAFile, "client-ca-file", options.clientCAFile,
+		"CA file for verifying client certificates in addition to the session CA",
+	)
+
+	cmd.Flags().StringVar(
+		&options.dataDir, "data-dir", options.dataDir,
+		"directory for persisting etcd data, bundles imported in a previous run are served without import",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.reimport, "reimport", options.reimport,
+		"remove data persisted in --data-dir for the served bundles and import them again",
+	)
+
+	cmd.Flags().StringVar(
+		&options.storageBackendType, "storage-backend", options.storageBackendType,
+		"storage used by API servers, one of: etcd, embedded. The embedded etcd runs in the "+
+			"troubleshoot-live process and doesn't require etcd binary",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&options.snapshots, "snapshot", options.snapshots,
+		"[ID=]PATH of snapshot created by \"snapshot save\" command, the bundle resources are restored "+
+			"from the snapshot instead of importing them. ID can be omitted when a single bundle is served",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.managementAPI, "management-api", options.managementAPI,
+		"serve management API under /management for loading and deleting bundles at runtime",
+	)
+
+	cmd.Flags().StringVar(
+		&options.managementUploadDir, "management-upload-dir", options.managementUploadDir,
+		"directory where bundle archives uploaded via management API are stored, defaults to temporary directory",
+	)
+
+	cmd.Flags().Int64Var(
+		&options.managementMaxUpload, "management-max-upload-size", options.managementMaxUpload,
+		"size limit in bytes of bundle archives uploaded via management API, 0 disables the limit",
+	)
+
 	return cmd
 }
 
-func runServe(bundlePath string, o *serveOptions, out output.Output) error {
-	supportBundle, err := bundle.New(bundlePath)
+func runServe(args []string, o *serveOptions, out output.Output) error {
+	if o.logsReplaySpeed < 0 {
+		return fmt.Errorf("invalid logs replay speed %v: must not be negative", o.logsReplaySpeed)
+	}
+	if err := validateStorageBackendType(o.storageBackendType); err != nil {
+		return err
+	}
+	if len(args) == 0 && !o.managementAPI {
+		return fmt.Errorf("at least one bundle path is required unless %q is enabled", "--management-api")
+	}
+
+	bundleArgs, err := parseBundleArgs(args)
 	if err != nil {
-		return fmt.Errorf("failed to get bundle from path %q: %w", bundlePath, err)
+		return err
 	}
 
-	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT)
-	defer done()
+	serving, err := prepareProxyServing(o)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := parseSnapshotArgs(o.snapshots, bundleArgs)
+	if err != nil {
+		return err
+	}
+
+	rr, err := resourceRewriter(o.rewriterRulesPath)
+	if err != nil {
+		return err
+	}
+	rewriterRules, err := readRewriterRules(o.rewriterRulesPath)
+	if err != nil {
+		return err
+	}
 
-	out.StartOperation("Starting k8s server")
-	testEnv, storageBackend, err := startK8sServer(ctx, supportBundle, out, o)
-	out.EndOperation(err == nil)
+	importOptions, err := o.importFilters.importerOptions()
 	if err != nil {
 		return err
 	}
 
+	proxyOptions := []proxy.Option{
+		proxy.WithLogsHandlerOptions(proxy.WithLogsReplaySpeed(o.logsReplaySpeed)),
+	}
+	if len(serving.authenticators) > 0 {
+		proxyOptions = append(proxyOptions, proxy.WithAuthenticators(serving.authenticators...))
+	}
+	if o.readOnly {
+		allowed := make([]schema.GroupResource, 0, len(o.readOnlyAllow))
+		for _, resource := range o.readOnlyAllow {
+			allowed = append(allowed, schema.ParseGroupResource(resource))
+		}
+		proxyOptions = append(proxyOptions, proxy.WithReadOnly(allowed...))
+	}
+
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer done()
+
+	loader := &bundleLoader{
+		opts:          o,
+		out:           out,
+		rr:            rr,
+		rewriterRules: rewriterRules,
+		proxyOptions:  proxyOptions,
+		importOptions: importOptions,
+		snapshots:     snapshots,
+	}
+	managerOptions := []manager.Option{manager.WithMaxUploadSize(o.managementMaxUpload)}
+	if o.managementUploadDir != "" {
+		managerOptions = append(managerOptions, manager.WithUploadDir(o.managementUploadDir))
+	}
+	bundles := manager.New(ctx, loader, managerOptions...)
 	defer func() {
-		if err := testEnv.Stop(); err != nil {
-			out.Error(err, "failed to stop k8s api server")
+		if err := bundles.Close(); err != nil {
+			out.Error(err, "failed to stop bundles")
 		}
-		if err := storageBackend.Stop(); err != nil {
+		if err := loader.Stop(); err != nil {
 			out.Error(err, "failed to stop storage backend")
 		}
 	}()
 
-	out.StartOperation("Importing bundle resources")
-	err = importer.ImportBundle(ctx, supportBundle, testEnv.Config, out)
-	out.EndOperation(err == nil)
-	if err != nil {
-		out.Error(err, "failed to import support bundle resources to API server")
-	}
+	contexts := make([]kubernetes.ProxyContext, 0, len(bundleArgs))
+	for _, arg := range bundleArgs {
+		out.StartOperation(fmt.Sprintf("Loading bundle %q", arg.id))
+		status, err := loadBundle(ctx, bundles, arg)
+		out.EndOperation(err == nil)
+		if err != nil {
+			return err
+		}
+		if status.Message != "" {
+			out.Warnf("Bundle %q: %s", arg.id, status.Message)
+		}
 
-	normalizedProxyPrefix, err := proxy.NormalizeHTTPPrefix(internalProxyHTTPPrefix)
-	if err != nil {
-		return fmt.Errorf("invalid proxy http prefix: %w", err)
-	}
-	proxyHTTPAddress := fmt.Sprintf("http://%s%s", o.proxyAddress, normalizedProxyPrefix)
-	kubeconfigPath, err := kubernetes.WriteProxyKubeconfig(proxyHTTPAddress, o.kubeconfigPath)
-	if err != nil {
-		return fmt.Errorf("failed to create kubeconfig: %w", err)
+		kubeconfig := serving.bundleKubeconfig(o.proxyAddress, arg.id)
+		contexts = append(contexts, kubernetes.ProxyContext{Name: arg.id, Config: kubeconfig})
+		out.Infof("Serving bundle %q on: %s", arg.id, kubeconfig.Host)
 	}
 
-	out.Infof("Running HTTPs proxy service on: %s", proxyHTTPAddress)
-	out.Infof("KUBECONFIG=%s", kubeconfigPath)
+	if len(contexts) > 0 {
+		kubeconfigPath, err := kubernetes.WriteProxyKubeconfigContexts(contexts, o.kubeconfigPath)
+		if err != nil {
+			return fmt.Errorf("failed to create kubeconfig: %w", err)
+		}
+		out.Infof("KUBECONFIG=%s", kubeconfigPath)
+	}
 
-	proxyHandler, err := proxy.New(testEnv.Config, supportBundle, rewriter.Default(), normalizedProxyPrefix)
-	if err != nil {
-		return fmt.Errorf("failed to initialize proxy handler: %w", err)
+	root := http.NewServeMux()
+	root.Handle("/", proxy.NewBundlesHandler(bundles.Handler))
+	if o.managementAPI {
+		managementHandler := manager.NewAPIHandler(bundles, func(id string) ([]byte, error) {
+			return kubernetes.ProxyKubeconfig([]kubernetes.ProxyContext{
+				{Name: id, Config: serving.bundleKubeconfig(o.proxyAddress, id)},
+			})
+		})
+		root.Handle(manager.APIHTTPPrefix+"/", proxy.RequireAuthentication(managementHandler, serving.authenticators...))
+		out.Infof("Serving management API on: %s://%s%s", serving.scheme(), o.proxyAddress, manager.APIHTTPPrefix)
 	}
-	loggedProxyHandler := handlers.LoggingHandler(out.InfoWriter(), proxyHandler)
+	loggedProxyHandler := handlers.LoggingHandler(out.InfoWriter(), root)
 
 	s := http.Server{
 		Addr:              o.proxyAddress,
 		Handler:           loggedProxyHandler,
 		ReadHeaderTimeout: time.Second * 5,
+		TLSConfig:         serving.tlsConfig,
 	}
 	go func() {
 		<-ctx.Done()
@@ -143,65 +349,81 @@ func runServe(bundlePath string, o *serveOptions, out output.Output) error {
 			out.Error(err, "failed to shutdown http server")
 		}
 	}()
+	if s.TLSConfig != nil {
+		return ignoreServerClosedError(s.ListenAndServeTLS("", ""))
+	}
 	return ignoreServerClosedError(s.ListenAndServe())
 }
 
-func ignoreServerClosedError(err error) error {
-	if errors.Is(err, http.ErrServerClosed) {
-		return nil
+// loadBundle registers the bundle with the manager and waits until it is
+// loaded.
+func loadBundle(ctx context.Context, bundles *manager.Manager, arg bundleArg) (manager.Status, error) {
+	if _, err := bundles.Add(arg.id, arg.path); err != nil {
+		return manager.Status{}, err
 	}
-	return err
-}
 
-func startK8sServer(
-	ctx context.Context,
-	supportBundle bundle.Bundle,
-	out output.Output,
-	opts *serveOptions,
-) (*envtest.Environment, envtest.StorageBackend, error) {
-	testEnv, err := envtest.Prepare(ctx, supportBundle, envtest.Arch(opts.envtestArch))
+	status, err := bundles.Wait(ctx, arg.id)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to prepare k8s environment: %w", err)
+		return status, err
 	}
-
-	testEnv.ControlPlane.GetAPIServer().Out = out.V(5).InfoWriter()
-	testEnv.ControlPlane.GetAPIServer().Err = out.V(5).InfoWriter()
-
-	serviceClusterIPRange, err := resolveServiceClusterIPRange(
-		opts.serviceClusterIPRange, supportBundle, out)
-	if err != nil {
-		return nil, nil, err
+	if status.Phase == manager.PhaseFailed {
+		return status, fmt.Errorf("failed to load bundle %q: %s", arg.id, status.Message)
 	}
-	if serviceClusterIPRange != "" {
-		testEnv.ControlPlane.GetAPIServer().Configure().Append("service-cluster-ip-range", serviceClusterIPRange)
+	return status, nil
+}
+
+// resourceRewriter returns the default rewriter extended with rules loaded
+// from rulesPath, if provided.
+func resourceRewriter(rulesPath string) (rewriter.ResourceRewriter, error) {
+	rr := rewriter.Default()
+	if rulesPath == "" {
+		return rr, nil
 	}
 
-	serviceNodePortRange, err := resolveServiceNodePortRange(
-		opts.serviceNodePortRange, supportBundle, out)
+	rules, err := rewriter.LoadRules(rulesPath)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	if serviceNodePortRange != "" {
-		testEnv.ControlPlane.GetAPIServer().Configure().Append("service-node-port-range", serviceNodePortRange)
+	return rewriter.Multi(rr, rules), nil
+}
+
+// readRewriterRules returns content of the rewriter rules file, empty if no
+// file is provided.
+func readRewriterRules(rulesPath string) (string, error) {
+	if rulesPath == "" {
+		return "", nil
 	}
 
-	storageBackend := envtest.NewLocalEtcdStorageBackend(testEnv.BinaryAssetsDirectory)
-	if err := storageBackend.Start(ctx); err != nil {
-		return nil, nil, fmt.Errorf("failed to start storage backend: %w", err)
+	data, err := os.ReadFile(rulesPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read rewriter rules: %w", err)
 	}
+	return string(data), nil
+}
 
-	_, err = testEnv.Start(ctx,
-		envtest.WithStorageBackend(storageBackend),
-		envtest.WithStorageID(defaultStorageID),
-	)
+// clockAnchorRewriter returns rewriter that shifts served timestamps by the
+// time elapsed since the bundle was collected. Returns nil if the collection
+// time can't be detected.
+func clockAnchorRewriter(supportBundle bundle.Bundle, out output.Output) (rewriter.ResourceRewriter, error) {
+	collectedAt, err := bundle.DetectCollectionTime(supportBundle)
 	if err != nil {
-		if stopErr := storageBackend.Stop(); stopErr != nil {
-			out.Error(stopErr, "failed to stop storage backend after api server startup failure")
-		}
-		return nil, nil, err
+		return nil, fmt.Errorf("failed to detect bundle collection time: %w", err)
+	}
+	if collectedAt.IsZero() {
+		out.Warn("Bundle collection time could not be detected, no events or object timestamps found in the bundle, served timestamps won't be shifted")
+		return nil, nil
 	}
 
-	return testEnv, storageBackend, nil
+	offset := time.Since(collectedAt).Truncate(time.Second)
+	out.V(1).Infof("Detected bundle collection time %s, shifting served timestamps by %s", collectedAt.UTC(), offset)
+	return rewriter.TimeShift(offset), nil
+}
+
+func ignoreServerClosedError(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
 func resolveServiceNodePortRange(
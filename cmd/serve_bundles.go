@@ -0,0 +1,572 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/spf13/afero"
+	"sigs.k8s.io/controller-runtime/tools/setup-envtest/versions"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/envtest"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/importer"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/manager"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/proxy"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/snapshot"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/utils"
+)
+
+// defaultBundleID is used when a single bundle is served without explicit ID.
+const defaultBundleID = "default"
+
+// Storage backends selectable with `--storage-backend` flag.
+const (
+	storageBackendEtcd     = "etcd"
+	storageBackendEmbedded = "embedded"
+)
+
+// validateStorageBackendType checks the `--storage-backend` flag value.
+func validateStorageBackendType(storageBackendType string) error {
+	switch storageBackendType {
+	case storageBackendEtcd, storageBackendEmbedded:
+		return nil
+	}
+	return fmt.Errorf("invalid storage backend %q: must be one of: %s, %s",
+		storageBackendType, storageBackendEtcd, storageBackendEmbedded)
+}
+
+// bundleArg is a bundle provided as `serve` command argument.
+type bundleArg struct {
+	id   string
+	path string
+}
+
+// parseBundleArgs parses `[ID=]PATH` bundle arguments. A single bundle without
+// ID is served as the default bundle, otherwise the ID is derived from the
+// bundle file name.
+func parseBundleArgs(args []string) ([]bundleArg, error) {
+	bundles := make([]bundleArg, 0, len(args))
+	seen := map[string]bool{}
+	for _, arg := range args {
+		id, path, explicit := strings.Cut(arg, "=")
+		if !explicit {
+			path = arg
+			id = manager.IDFromPath(path)
+			if len(args) == 1 {
+				id = defaultBundleID
+			}
+			id = manager.UniqueID(id, seen)
+		}
+
+		if err := manager.ValidateID(id); err != nil {
+			return nil, err
+		}
+		if path == "" {
+			return nil, fmt.Errorf("missing path for bundle %q", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate bundle id %q", id)
+		}
+		seen[id] = true
+
+		bundles = append(bundles, bundleArg{id: id, path: path})
+	}
+	return bundles, nil
+}
+
+// parseSnapshotArgs parses `[ID=]PATH` snapshot arguments to paths of
+// snapshots by bundle ID. The ID can be omitted when a single bundle is served.
+func parseSnapshotArgs(args []string, bundles []bundleArg) (map[string]string, error) {
+	snapshots := map[string]string{}
+	for _, arg := range args {
+		id, path, explicit := strings.Cut(arg, "=")
+		if !explicit {
+			if len(bundles) != 1 {
+				return nil, fmt.Errorf("snapshot %q must be provided as ID=PATH when serving multiple bundles", arg)
+			}
+			id, path = bundles[0].id, arg
+		}
+
+		if !slices.ContainsFunc(bundles, func(b bundleArg) bool { return b.id == id }) {
+			return nil, fmt.Errorf("snapshot %q is provided for unknown bundle %q", path, id)
+		}
+		if _, ok := snapshots[id]; ok {
+			return nil, fmt.Errorf("duplicate snapshot for bundle %q", id)
+		}
+		snapshots[id] = path
+	}
+	return snapshots, nil
+}
+
+// bundleLoader starts an API server for each loaded bundle. The API servers
+// share one storage backend which is created with the first API server.
+type bundleLoader struct {
+	opts *serveOptions
+	out  output.Output
+	rr   rewriter.ResourceRewriter
+	// rewriterRules are the additional rewriter rules of rr in YAML format.
+	rewriterRules string
+	proxyOptions  []proxy.Option
+	// importOptions are applied when importing bundles, e.g. import filters.
+	importOptions []importer.Option
+	// snapshots are paths of snapshots from which bundles with given ID are
+	// restored instead of importing them.
+	snapshots map[string]string
+
+	mu             sync.Mutex
+	storageBackend envtest.StorageBackend
+}
+
+var _ manager.Loader = &bundleLoader{}
+
+// loadedBundle is a bundle served by a started API server.
+type loadedBundle struct {
+	bundle     bundle.Bundle
+	testEnv    *envtest.Environment
+	bundleHash string
+	storageID  string
+	k8sVersion versions.Selector
+	rr         rewriter.ResourceRewriter
+	// importReport is nil when the bundle resources weren't imported by this
+	// process.
+	importReport *importer.Report
+}
+
+func (l *bundleLoader) Load(
+	ctx context.Context,
+	req manager.LoadRequest,
+	report manager.Reporter,
+) (manager.Instance, error) {
+	loaded, err := l.start(ctx, req, report)
+	if err != nil {
+		return nil, err
+	}
+
+	handler, err := l.proxyHandler(ctx, req.ID, loaded)
+	if err != nil {
+		if stopErr := loaded.testEnv.Stop(); stopErr != nil {
+			l.out.Error(stopErr, fmt.Sprintf("failed to stop k8s api server of bundle %q", req.ID))
+		}
+		return nil, err
+	}
+
+	return &bundleInstance{handler: handler, testEnv: loaded.testEnv}, nil
+}
+
+// Release removes the bundle resources from the storage backend. Data
+// persisted in the data dir are kept so that the bundle can be served again
+// without import.
+func (l *bundleLoader) Release(ctx context.Context, req manager.LoadRequest) error {
+	if l.opts.dataDir != "" {
+		return nil
+	}
+
+	l.mu.Lock()
+	storageBackend := l.storageBackend
+	l.mu.Unlock()
+	if storageBackend == nil {
+		return nil
+	}
+	return storageBackend.Delete(ctx, req.StorageID)
+}
+
+// start starts API server for the bundle and imports the bundle resources,
+// unless they were imported in a previous run or are restored from snapshot.
+func (l *bundleLoader) start(
+	ctx context.Context,
+	req manager.LoadRequest,
+	report manager.Reporter,
+) (*loadedBundle, error) {
+	supportBundle, err := bundle.New(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle from path %q: %w", req.Path, err)
+	}
+
+	var snap *snapshot.Snapshot
+	if snapshotPath, ok := l.snapshots[req.ID]; ok {
+		f, err := os.Open(snapshotPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open snapshot: %w", err)
+		}
+		defer f.Close()
+		snap, err = snapshot.Read(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot %q: %w", snapshotPath, err)
+		}
+	}
+
+	loaded := &loadedBundle{
+		bundle:    supportBundle,
+		storageID: req.StorageID,
+		rr:        l.rr,
+	}
+	rewriterRules := l.rewriterRules
+	if snap != nil && snap.Metadata.RewriterRules != "" && l.opts.rewriterRulesPath == "" {
+		rules, err := rewriter.ParseRules([]byte(snap.Metadata.RewriterRules))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load snapshot rewriter rules: %w", err)
+		}
+		loaded.rr = rewriter.Multi(rewriter.Default(), rules)
+		rewriterRules = snap.Metadata.RewriterRules
+	}
+
+	switch {
+	case l.opts.kubernetesVersion != "":
+		loaded.k8sVersion, err = envtest.ParseK8sVersion(l.opts.kubernetesVersion)
+		if err != nil {
+			return nil, err
+		}
+	case snap != nil:
+		loaded.k8sVersion, err = envtest.ParseK8sVersion(snap.Metadata.KubernetesVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot: %w", err)
+		}
+	default:
+		loaded.k8sVersion, err = envtest.DetectK8sVersion(supportBundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect k8s version, it can be provided with %q flag: %w",
+				"--kubernetes-version", err)
+		}
+		l.out.V(1).Infof("Detected %q k8s version of bundle %q", loaded.k8sVersion, req.ID)
+	}
+	report(func(s *manager.Status) {
+		s.KubernetesVersion = loaded.k8sVersion.String()
+	})
+
+	if l.opts.dataDir != "" || (snap != nil && snap.Metadata.BundleHash != "") {
+		loaded.bundleHash, err = bundle.ContentHash(supportBundle)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if snap != nil && snap.Metadata.BundleHash != "" && snap.Metadata.BundleHash != loaded.bundleHash {
+		l.out.Warnf("Snapshot %q was not created from bundle %q, served logs and timestamps may not match resources",
+			l.snapshots[req.ID], req.ID)
+	}
+
+	importMarker := ""
+	if l.opts.dataDir != "" {
+		// Persisted data are keyed by bundle ID, bundle content, import
+		// filters and rewriter rules. The bundle is served from the stored
+		// data when it is loaded with the same ID, which is the file name of
+		// the bundle when multiple bundles are served, and the same content,
+		// filters and rules. Bundles with identical content served under
+		// different IDs don't share the data, so that re-import of one doesn't
+		// wipe data of the other.
+		key := req.ID + "\n" + loaded.bundleHash
+		if filtersKey := l.opts.importFilters.key(); filtersKey != "" {
+			key += "\n" + filtersKey
+		}
+		if rewriterRules != "" {
+			// The rules change objects stored on import.
+			rulesSum := sha256.Sum256([]byte(rewriterRules))
+			key += "\nrules=" + hex.EncodeToString(rulesSum[:])
+		}
+		sum := sha256.Sum256([]byte(key))
+		loaded.storageID = hex.EncodeToString(sum[:])[:32]
+		importMarker = l.importMarkerPath(loaded.storageID)
+	}
+
+	imported := false
+	switch {
+	case importMarker != "" && l.opts.reimport:
+		if err := os.Remove(importMarker); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove bundle import record: %w", err)
+		}
+	case importMarker != "":
+		imported, err = afero.Exists(afero.NewOsFs(), importMarker)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	prepareStorage := func(ctx context.Context, storageBackend envtest.StorageBackend) error {
+		switch {
+		case snap != nil && !imported:
+			return snap.Restore(ctx, storageBackend, loaded.storageID)
+		case importMarker != "" && l.opts.reimport:
+			return storageBackend.Delete(ctx, loaded.storageID)
+		}
+		return nil
+	}
+
+	report(func(s *manager.Status) {
+		s.Phase = manager.PhaseStarting
+	})
+	loaded.testEnv, err = l.startK8sServer(ctx, supportBundle, loaded.k8sVersion, loaded.storageID, prepareStorage)
+	if err != nil {
+		return nil, err
+	}
+	if k8sVersion := loaded.testEnv.K8sVersion(); k8sVersion.String() != loaded.k8sVersion.String() {
+		loaded.k8sVersion = k8sVersion
+		report(func(s *manager.Status) {
+			s.KubernetesVersion = k8sVersion.String()
+		})
+	}
+
+	switch {
+	case imported:
+		l.out.Infof("Bundle %q resources were imported in a previous run, skipping import", req.ID)
+		report(func(s *manager.Status) {
+			s.Message = "resources were imported in a previous run"
+		})
+	case snap != nil:
+		l.out.Infof("Bundle %q resources were restored from snapshot, skipping import", req.ID)
+		report(func(s *manager.Status) {
+			s.Message = "resources were restored from snapshot"
+		})
+		if importMarker != "" {
+			if err := writeImportMarker(importMarker, req.Path); err != nil {
+				l.out.Error(err, "failed to record bundle import")
+			}
+		}
+	default:
+		report(func(s *manager.Status) {
+			s.Phase = manager.PhaseImporting
+		})
+		loaded.importReport = &importer.Report{}
+		importOptions := append(slices.Clone(l.importOptions),
+			importer.WithRewriter(loaded.rr),
+			importer.WithProgress(func(p importer.Progress) {
+				report(func(s *manager.Status) {
+					s.Progress = p
+				})
+			}),
+			importer.WithReport(loaded.importReport),
+		)
+		err = importer.ImportBundle(ctx, supportBundle, loaded.testEnv.Config, l.out, importOptions...)
+		printImportSummary(l.out, fmt.Sprintf("Import summary of bundle %q:", req.ID), loaded.importReport)
+		switch {
+		case err != nil:
+			l.out.Error(err, fmt.Sprintf("failed to import support bundle %q resources to API server", req.ID))
+			report(func(s *manager.Status) {
+				s.Message = fmt.Sprintf("failed to import some resources: %s", utils.MaxErrorString(err, 200))
+			})
+		case importMarker != "":
+			if err := writeImportMarker(importMarker, req.Path); err != nil {
+				l.out.Error(err, "failed to record bundle import")
+			}
+		}
+	}
+
+	return loaded, nil
+}
+
+func (l *bundleLoader) proxyHandler(
+	ctx context.Context,
+	id string,
+	loaded *loadedBundle,
+) (http.Handler, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	rr := loaded.rr
+	proxyOptions := append(slices.Clone(l.proxyOptions), proxy.WithImportReport(loaded.importReport))
+	if l.opts.anchorClock {
+		timeShift, err := clockAnchorRewriter(loaded.bundle, l.out)
+		if err != nil {
+			return nil, err
+		}
+		if timeShift != nil {
+			rr = rewriter.Multi(rr, timeShift)
+			proxyOptions = append(proxyOptions, proxy.WithRequestRewriter(timeShift))
+		}
+	}
+
+	handler, err := proxy.New(loaded.testEnv.Config, loaded.bundle, rr, proxy.BundleHTTPPrefix(id), proxyOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize proxy handler for bundle %q: %w", id, err)
+	}
+	return handler, nil
+}
+
+// startK8sServer starts API server for the bundle. API servers are started
+// one at a time as preparing the environment may download envtest assets. The
+// prepareStorage is called with the started storage backend before the API
+// server is started.
+func (l *bundleLoader) startK8sServer(
+	ctx context.Context,
+	supportBundle bundle.Bundle,
+	k8sVersion versions.Selector,
+	storageID string,
+	prepareStorage func(context.Context, envtest.StorageBackend) error,
+) (*envtest.Environment, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	testEnv, err := l.prepareEnvironment(ctx, k8sVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare k8s environment: %w", err)
+	}
+
+	testEnv.ControlPlane.GetAPIServer().Out = l.out.V(5).InfoWriter()
+	testEnv.ControlPlane.GetAPIServer().Err = l.out.V(5).InfoWriter()
+
+	serviceClusterIPRange, err := resolveServiceClusterIPRange(
+		l.opts.serviceClusterIPRange, supportBundle, l.out)
+	if err != nil {
+		return nil, err
+	}
+	if serviceClusterIPRange != "" {
+		testEnv.ControlPlane.GetAPIServer().Configure().Append("service-cluster-ip-range", serviceClusterIPRange)
+	}
+
+	serviceNodePortRange, err := resolveServiceNodePortRange(
+		l.opts.serviceNodePortRange, supportBundle, l.out)
+	if err != nil {
+		return nil, err
+	}
+	if serviceNodePortRange != "" {
+		testEnv.ControlPlane.GetAPIServer().Configure().Append("service-node-port-range", serviceNodePortRange)
+	}
+
+	if l.storageBackend == nil {
+		storageBackend := l.newStorageBackend(testEnv.BinaryAssetsDirectory)
+		if err := storageBackend.Start(ctx); err != nil {
+			return nil, fmt.Errorf("failed to start storage backend: %w", err)
+		}
+		l.storageBackend = storageBackend
+	}
+
+	if err := prepareStorage(ctx, l.storageBackend); err != nil {
+		return nil, err
+	}
+
+	_, err = testEnv.Start(ctx,
+		envtest.WithStorageBackend(l.storageBackend),
+		envtest.WithStorageID(storageID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return testEnv, nil
+}
+
+// prepareEnvironment prepares k8s environment from the envtest assets for the
+// k8s version or from the kube-apiserver binary provided by options.
+func (l *bundleLoader) prepareEnvironment(
+	ctx context.Context,
+	k8sVersion versions.Selector,
+) (*envtest.Environment, error) {
+	if l.opts.kubeAPIServerBinary != "" {
+		testEnv, err := envtest.PrepareBinary(ctx, l.opts.kubeAPIServerBinary)
+		if err != nil {
+			return nil, err
+		}
+		if binaryVersion := testEnv.K8sVersion().AsConcrete(); !k8sVersion.Matches(*binaryVersion) {
+			l.out.Warnf("kube-apiserver binary version %s doesn't match bundle k8s version %s",
+				binaryVersion, k8sVersion)
+		}
+		return testEnv, nil
+	}
+
+	opts := []envtest.Option{envtest.Arch(l.opts.envtestArch)}
+	if l.opts.assetsDir != "" {
+		opts = append(opts, envtest.AssetsDir(l.opts.assetsDir))
+	}
+	if l.opts.noDownload {
+		opts = append(opts, envtest.NoDownload())
+	}
+	if l.opts.verifyChecksum {
+		opts = append(opts, envtest.VerifyChecksum())
+	}
+	testEnv, err := envtest.PrepareVersion(ctx, k8sVersion, opts...)
+	if err != nil && l.opts.noDownload {
+		return nil, fmt.Errorf("%w, assets can be downloaded with \"assets fetch\" command", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if testEnv.K8sVersion().String() != k8sVersion.String() {
+		l.out.Warnf("Envtest assets for k8s version %s are not available, using nearest version %s",
+			k8sVersion, testEnv.K8sVersion())
+	}
+	return testEnv, nil
+}
+
+// newStorageBackend creates the storage backend selected by options. Data of
+// each backend are persisted in a separate directory of the data dir.
+func (l *bundleLoader) newStorageBackend(binaryAssetsDirectory string) envtest.StorageBackend {
+	var storageOptions []envtest.LocalEtcdStorageOption
+	if l.opts.storageBackendType == storageBackendEmbedded {
+		if l.opts.dataDir != "" {
+			storageOptions = append(storageOptions,
+				envtest.WithLocalEtcdDataDir(filepath.Join(l.opts.dataDir, storageBackendEmbedded, "etcd")))
+		}
+		return envtest.NewEmbeddedEtcdStorageBackend(storageOptions...)
+	}
+
+	if l.opts.etcdBinary != "" {
+		storageOptions = append(storageOptions, envtest.WithLocalEtcdBinary(l.opts.etcdBinary))
+	}
+	if l.opts.dataDir != "" {
+		storageOptions = append(storageOptions, envtest.WithLocalEtcdDataDir(filepath.Join(l.opts.dataDir, "etcd")))
+	}
+	return envtest.NewLocalEtcdStorageBackend(binaryAssetsDirectory, storageOptions...)
+}
+
+// importMarkerPath returns path of the file recording that the bundle
+// resources were imported to the persisted storage.
+func (l *bundleLoader) importMarkerPath(storageID string) string {
+	if l.opts.storageBackendType == storageBackendEmbedded {
+		return filepath.Join(l.opts.dataDir, storageBackendEmbedded, "imported", storageID)
+	}
+	return filepath.Join(l.opts.dataDir, "imported", storageID)
+}
+
+// Stop stops the storage backend, API servers must be stopped before.
+func (l *bundleLoader) Stop() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.storageBackend == nil {
+		return nil
+	}
+	return l.storageBackend.Stop()
+}
+
+// printImportSummary prints summary table of the import report.
+func printImportSummary(out output.Output, title string, report *importer.Report) {
+	var b strings.Builder
+	b.WriteString(title + "\n")
+	if err := report.WriteSummary(&b); err != nil {
+		out.Error(err, "failed to print import summary")
+		return
+	}
+	out.Info(strings.TrimSuffix(b.String(), "\n"))
+}
+
+// writeImportMarker records that the bundle resources were imported to the
+// persisted storage.
+func writeImportMarker(path, bundlePath string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(bundlePath+"\n"), 0o644)
+}
+
+type bundleInstance struct {
+	handler http.Handler
+	testEnv *envtest.Environment
+}
+
+func (i *bundleInstance) Handler() http.Handler {
+	return i.handler
+}
+
+func (i *bundleInstance) Stop() error {
+	return i.testEnv.Stop()
+}
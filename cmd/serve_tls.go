@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/certs"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/proxy"
+)
+
+const (
+	authNone       = ""
+	authToken      = "token"
+	authClientCert = "client-cert"
+
+	proxyClientUserName = "troubleshoot-live"
+)
+
+// proxyServing holds configuration for serving the proxy and for the clients
+// connecting to the proxy.
+type proxyServing struct {
+	tlsConfig      *tls.Config
+	kubeconfig     *rest.Config
+	authenticators []proxy.Authenticator
+}
+
+func (s *proxyServing) scheme() string {
+	if s.tlsConfig != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// bundleKubeconfig returns client config for accessing the bundle via proxy.
+func (s *proxyServing) bundleKubeconfig(proxyAddress, id string) *rest.Config {
+	cfg := rest.CopyConfig(s.kubeconfig)
+	cfg.Host = fmt.Sprintf("%s://%s%s", s.scheme(), proxyAddress, proxy.BundleHTTPPrefix(id))
+	return cfg
+}
+
+// prepareProxyServing configures TLS and authentication of the proxy server
+// based on serve options. Certificates that are not provided by the user are
+// generated for the session.
+func prepareProxyServing(o *serveOptions) (*proxyServing, error) {
+	useTLS := o.tls || o.tlsCertFile != "" || o.tlsKeyFile != ""
+	if (o.tlsCertFile == "") != (o.tlsKeyFile == "") {
+		return nil, fmt.Errorf("both --tls-cert-file and --tls-key-file must be provided")
+	}
+
+	switch o.auth {
+	case authNone, authToken:
+	case authClientCert:
+		if !useTLS {
+			return nil, fmt.Errorf("%q authentication requires TLS", authClientCert)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported authentication %q, must be one of %q or %q", o.auth, authToken, authClientCert)
+	}
+
+	serving := &proxyServing{
+		kubeconfig: &rest.Config{},
+	}
+
+	var ca *certs.CA
+	if useTLS && (o.tlsCertFile == "" || o.auth == authClientCert) {
+		var err error
+		ca, err = certs.NewCA("troubleshoot-live-ca")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if useTLS {
+		serverCert, caData, err := servingCertificate(o, ca)
+		if err != nil {
+			return nil, err
+		}
+		serving.tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		serving.kubeconfig.CAData = caData
+	}
+
+	switch o.auth {
+	case authToken:
+		token := o.authToken
+		if token == "" {
+			var err error
+			token, err = randomToken()
+			if err != nil {
+				return nil, err
+			}
+		}
+		serving.kubeconfig.BearerToken = token
+		serving.authenticators = append(serving.authenticators, proxy.BearerTokenAuthenticator(token))
+	case authClientCert:
+		clientCAs, err := clientCertPool(o.clientCAFile, ca)
+		if err != nil {
+			return nil, err
+		}
+		certPEM, keyPEM, err := ca.NewClientCert(proxyClientUserName)
+		if err != nil {
+			return nil, err
+		}
+		serving.tlsConfig.ClientCAs = clientCAs
+		// Health checks are served without client certificates.
+		serving.tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		serving.kubeconfig.CertData = certPEM
+		serving.kubeconfig.KeyData = keyPEM
+		serving.authenticators = append(serving.authenticators, proxy.ClientCertAuthenticator())
+	}
+
+	return serving, nil
+}
+
+func servingCertificate(o *serveOptions, ca *certs.CA) (tls.Certificate, []byte, error) {
+	if o.tlsCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.tlsCertFile, o.tlsKeyFile)
+		if err != nil {
+			return tls.Certificate{}, nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+
+		var caData []byte
+		if o.tlsCAFile != "" {
+			caData, err = os.ReadFile(o.tlsCAFile)
+			if err != nil {
+				return tls.Certificate{}, nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+			}
+		}
+		return cert, caData, nil
+	}
+
+	cert, err := ca.NewServingCert(servingCertHosts(o.proxyAddress, o.tlsSANs)...)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to generate serving certificate: %w", err)
+	}
+	return cert, ca.CertPEM(), nil
+}
+
+func servingCertHosts(proxyAddress string, extraHosts []string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if host, _, err := net.SplitHostPort(proxyAddress); err == nil && host != "" {
+		hosts = append(hosts, host)
+	}
+	return append(hosts, extraHosts...)
+}
+
+func clientCertPool(clientCAFile string, ca *certs.CA) (*x509.CertPool, error) {
+	pool := ca.CertPool()
+	if clientCAFile == "" {
+		return pool, nil
+	}
+
+	data, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in client CA file %q", clientCAFile)
+	}
+	return pool, nil
+}
+
+func randomToken() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(data), nil
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/discovery"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/envtest"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/manager"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/snapshot"
+)
+
+type snapshotSaveOptions struct {
+	serveOptions
+	outputPath string
+}
+
+// NewSnapshotCommand groups commands for snapshots of imported bundles.
+func NewSnapshotCommand(out output.Output) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Manages snapshots of API server state with imported bundle resources",
+	}
+	cmd.AddCommand(newSnapshotSaveCommand(out))
+	return cmd
+}
+
+func newSnapshotSaveCommand(out output.Output) *cobra.Command {
+	options := &snapshotSaveOptions{
+		serveOptions: serveOptions{
+			envtestArch:        runtime.GOARCH,
+			storageBackendType: storageBackendEtcd,
+		},
+		outputPath: "./support-bundle.snapshot",
+	}
+
+	cmd := &cobra.Command{
+		Use:   "save SUPPORT_BUNDLE_PATH",
+		Short: "Imports bundle resources and saves the API server state to a snapshot file",
+		Long: "Imports bundle resources to a local envtest based Kubernetes API server and saves the " +
+			"stored data together with the Kubernetes version and rewriter rules to a snapshot file. " +
+			"The bundle can be then served from the snapshot with \"serve --snapshot\" without importing it.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSnapshotSave(args[0], options, out)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&options.outputPath, "output", options.outputPath,
+		"where to write the snapshot",
+	)
+
+	cmd.Flags().StringVar(
+		&options.envtestArch, "envtest-arch", options.envtestArch,
+		"arch value for k8s server assets",
+	)
+
+	addEnvtestAssetsFlags(cmd, &options.serveOptions)
+
+	cmd.Flags().StringVar(
+		&options.serviceClusterIPRange, "service-cluster-ip-range", options.serviceClusterIPRange,
+		"override k8s api server service ClusterIP range. Mask must be >= /12 range.",
+	)
+
+	cmd.Flags().StringVar(
+		&options.serviceNodePortRange, "service-node-port-range", options.serviceNodePortRange,
+		"override k8s api server service node port range",
+	)
+
+	cmd.Flags().StringVar(
+		&options.rewriterRulesPath, "rewriter-rules", options.rewriterRulesPath,
+		"path to YAML file with additional rewriter rules applied to imported objects, the rules are stored in the snapshot",
+	)
+
+	addImportFilterFlags(cmd, &options.importFilters)
+
+	cmd.Flags().StringVar(
+		&options.dataDir, "data-dir", options.dataDir,
+		"directory for persisting etcd data, bundle imported in a previous run is saved without import",
+	)
+
+	cmd.Flags().StringVar(
+		&options.storageBackendType, "storage-backend", options.storageBackendType,
+		"storage used by API server, one of: etcd, embedded",
+	)
+
+	return cmd
+}
+
+func runSnapshotSave(bundlePath string, o *snapshotSaveOptions, out output.Output) error {
+	if err := validateStorageBackendType(o.storageBackendType); err != nil {
+		return err
+	}
+	rr, err := resourceRewriter(o.rewriterRulesPath)
+	if err != nil {
+		return err
+	}
+	importOptions, err := o.importFilters.importerOptions()
+	if err != nil {
+		return err
+	}
+	rules, err := readRewriterRules(o.rewriterRulesPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer done()
+
+	loader := &bundleLoader{
+		opts:          &o.serveOptions,
+		out:           out,
+		rr:            rr,
+		rewriterRules: rules,
+		importOptions: importOptions,
+	}
+	defer func() {
+		if err := loader.Stop(); err != nil {
+			out.Error(err, "failed to stop storage backend")
+		}
+	}()
+
+	status := manager.Status{}
+	out.StartOperation("Importing bundle resources")
+	loaded, err := loader.start(ctx, manager.LoadRequest{
+		ID:        defaultBundleID,
+		StorageID: defaultBundleID,
+		Path:      bundlePath,
+	}, func(update func(*manager.Status)) {
+		update(&status)
+	})
+	out.EndOperation(err == nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := loaded.testEnv.Stop(); err != nil {
+			out.Error(err, "failed to stop k8s api server")
+		}
+	}()
+	if status.Message != "" {
+		out.Warnf("Bundle %q: %s", bundlePath, status.Message)
+	}
+
+	meta, err := snapshotMetadata(loaded, rules)
+	if err != nil {
+		return err
+	}
+
+	out.StartOperation("Saving snapshot")
+	err = writeSnapshotFile(ctx, o.outputPath, meta, loader.storageBackend, loaded.storageID)
+	out.EndOperation(err == nil)
+	if err != nil {
+		return err
+	}
+
+	out.Infof("Snapshot path: %s", o.outputPath)
+	return nil
+}
+
+func snapshotMetadata(loaded *loadedBundle, rewriterRules string) (snapshot.Metadata, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(loaded.testEnv.Config)
+	if err != nil {
+		return snapshot.Metadata{}, err
+	}
+	serverVersion, err := discoveryClient.ServerVersion()
+	if err != nil {
+		return snapshot.Metadata{}, fmt.Errorf("failed to get k8s api server version: %w", err)
+	}
+
+	bundleHash := loaded.bundleHash
+	if bundleHash == "" {
+		bundleHash, err = bundle.ContentHash(loaded.bundle)
+		if err != nil {
+			return snapshot.Metadata{}, err
+		}
+	}
+
+	return snapshot.Metadata{
+		KubernetesVersion: serverVersion.GitVersion,
+		BundleHash:        bundleHash,
+		RewriterRules:     rewriterRules,
+		CreatedAt:         time.Now().UTC(),
+	}, nil
+}
+
+// writeSnapshotFile writes snapshot to the path. Partially written file is
+// removed on failure.
+func writeSnapshotFile(
+	ctx context.Context,
+	path string,
+	meta snapshot.Metadata,
+	storageBackend envtest.StorageBackend,
+	storageID string,
+) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+
+	err = snapshot.Write(ctx, f, meta, storageBackend, storageID)
+	err = errors.Join(err, f.Close())
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+	return nil
+}
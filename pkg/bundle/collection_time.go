@@ -0,0 +1,121 @@
+package bundle
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// eventTimestampFields lists event fields that record when the event was
+// observed. The newest value across all events approximates the time when the
+// bundle was collected.
+var eventTimestampFields = [][]string{
+	{"lastTimestamp"},
+	{"firstTimestamp"},
+	{"eventTime"},
+	{"series", "lastObservedTime"},
+	{"metadata", "creationTimestamp"},
+}
+
+// conditionTimestampFields lists fields of status conditions that record when
+// the condition was observed.
+var conditionTimestampFields = []string{
+	"lastTransitionTime",
+	"lastHeartbeatTime",
+}
+
+// DetectCollectionTime attempts to determine when the bundle was collected.
+// Support bundles do not store the collection time explicitly, so the function
+// uses the newest timestamp of events stored in the bundle. Bundles without
+// events fall back to the newest creation or condition timestamp of the other
+// objects, which is older than the collection time. A zero time is returned
+// when no timestamp could be found.
+func DetectCollectionTime(b Bundle) (time.Time, error) {
+	clusterResources := b.Layout().ClusterResources()
+	newest, err := newestTimestamp(b, filepath.Join(clusterResources, "events"), newestEventTimestamp)
+	if err != nil || !newest.IsZero() {
+		return newest, err
+	}
+
+	return newestTimestamp(b, clusterResources, newestObjectTimestamp)
+}
+
+// newestTimestamp returns the newest timestamp of objects stored in the bundle
+// directory.
+func newestTimestamp(b Bundle, dir string, timestampFn func(*unstructured.Unstructured) time.Time) (time.Time, error) {
+	if ok, err := afero.DirExists(b, dir); err != nil || !ok {
+		return time.Time{}, err
+	}
+
+	newest := time.Time{}
+	err := afero.Walk(b, dir, func(path string, info fs.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+
+		if info.IsDir() || strings.HasSuffix(strings.TrimSuffix(info.Name(), filepath.Ext(path)), "-errors") {
+			return nil
+		}
+
+		list, err := LoadResourcesFromFile(b, path)
+		if err != nil {
+			// Files that cannot be parsed are reported by the importer.
+			return nil
+		}
+
+		for i := range list.Items {
+			if t := timestampFn(&list.Items[i]); t.After(newest) {
+				newest = t
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return newest, nil
+}
+
+func newestEventTimestamp(u *unstructured.Unstructured) time.Time {
+	newest := time.Time{}
+	for _, field := range eventTimestampFields {
+		newest = newer(newest, u.Object, field...)
+	}
+	return newest
+}
+
+func newestObjectTimestamp(u *unstructured.Unstructured) time.Time {
+	newest := newer(time.Time{}, u.Object, "metadata", "creationTimestamp")
+
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, field := range conditionTimestampFields {
+			newest = newer(newest, condition, field)
+		}
+	}
+	return newest
+}
+
+// newer returns the timestamp stored in the object field if it's newer than
+// the given time.
+func newer(newest time.Time, object map[string]any, field ...string) time.Time {
+	value, ok, err := unstructured.NestedString(object, field...)
+	if err != nil || !ok || value == "" {
+		return newest
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil || !t.After(newest) {
+		return newest
+	}
+	return t
+}
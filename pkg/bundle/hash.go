@@ -0,0 +1,39 @@
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/spf13/afero"
+)
+
+// ContentHash returns hash of the bundle files and their content. Bundles with
+// the same content have the same hash regardless of their location or
+// archive format.
+func ContentHash(b Bundle) (string, error) {
+	h := sha256.New()
+	err := afero.Walk(b, "/", func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		fmt.Fprintf(h, "%s\x00%d\x00", path, info.Size())
+		f, err := b.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to compute bundle content hash: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -55,6 +55,8 @@ func parseJSONList(data []byte, path string) (*unstructured.UnstructuredList, er
 		return list, nil
 	}
 	errs := []error{err}
+	// Failed unmarshal may leave decoded items in the list.
+	list = &unstructured.UnstructuredList{}
 
 	// Format:
 	// - no GVK info in objects
@@ -0,0 +1,29 @@
+package bundle_test
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+)
+
+func TestLoadResourcesFromFile_ListWithoutTypeMeta(t *testing.T) {
+	// Items are decoded by the List unmarshal before it fails on the missing
+	// kind of the list.
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "pods/default.json", []byte(`{
+		"items": [
+			{"metadata": {"name": "a", "namespace": "default"}},
+			{"metadata": {"name": "b", "namespace": "default"}}
+		]
+	}`), 0o644))
+
+	list, err := bundle.LoadResourcesFromFile(fs, "pods/default.json")
+	require.NoError(t, err)
+	require.Len(t, list.Items, 2)
+	assert.Equal(t, "a", list.Items[0].GetName())
+	assert.Equal(t, "b", list.Items[1].GetName())
+}
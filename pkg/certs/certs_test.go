@@ -0,0 +1,53 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServingCert(t *testing.T) {
+	ca, err := NewCA("test-ca")
+	require.NoError(t, err)
+
+	cert, err := ca.NewServingCert("localhost", "127.0.0.1")
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost"}, leaf.DNSNames)
+	require.Len(t, leaf.IPAddresses, 1)
+	assert.Equal(t, "127.0.0.1", leaf.IPAddresses[0].String())
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(ca.CertPEM()))
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: "localhost", Roots: pool})
+	assert.NoError(t, err)
+}
+
+func TestClientCert(t *testing.T) {
+	ca, err := NewCA("test-ca")
+	require.NoError(t, err)
+
+	certPEM, keyPEM, err := ca.NewClientCert("user", "group")
+	require.NoError(t, err)
+
+	_, err = tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	block, _ := pem.Decode(certPEM)
+	require.NotNil(t, block)
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, "user", leaf.Subject.CommonName)
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:     ca.CertPool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.NoError(t, err)
+}
@@ -2,11 +2,14 @@ package envtest
 
 import (
 	"encoding/json"
+	"fmt"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	versions "sigs.k8s.io/controller-runtime/tools/setup-envtest/versions"
 
 	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
@@ -50,17 +53,42 @@ func selectorFromSemver(sv *semver.Version) versions.Selector {
 	}
 }
 
+// ParseK8sVersion parses k8s version, e.g. `v1.25.5`, to a selector of the
+// envtest assets for the same minor version.
+func ParseK8sVersion(version string) (versions.Selector, error) {
+	sv, err := semver.NewVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid k8s version %q: %w", version, err)
+	}
+	return selectorFromSemver(sv), nil
+}
+
 // DetectK8sVersion attempts to load k8s server version from which was bundle
-// collected.
+// collected. When the cluster info is missing or malformed the version is
+// inferred from the newest kubelet version of bundle nodes.
 func DetectK8sVersion(b bundle.Bundle) (versions.Selector, error) {
-	data, err := afero.ReadFile(b, filepath.Join(b.Layout().ClusterInfo(), "cluster_version.json"))
+	selector, err := detectClusterInfoVersion(b)
+	if err == nil {
+		return selector, nil
+	}
+
+	selector, nodesErr := detectNodesVersion(b)
+	if nodesErr != nil {
+		return nil, fmt.Errorf("%w, failed to infer version from nodes: %w", err, nodesErr)
+	}
+	return selector, nil
+}
+
+func detectClusterInfoVersion(b bundle.Bundle) (versions.Selector, error) {
+	path := filepath.Join(b.Layout().ClusterInfo(), "cluster_version.json")
+	data, err := afero.ReadFile(b, path)
 	if err != nil {
 		return nil, err
 	}
 
 	i := &clusterInfo{}
 	if err := json.Unmarshal(data, &i); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to parse %q: %w", path, err)
 	}
 
 	if sv, err := semver.NewVersion(i.VersionString); err == nil {
@@ -71,11 +99,42 @@ func DetectK8sVersion(b bundle.Bundle) (versions.Selector, error) {
 		return selectorFromSemver(sv), nil
 	}
 
-	major, _ := strconv.Atoi(i.Info.Major)
-	minor, _ := strconv.Atoi(i.Info.Minor)
+	// Managed providers report minor version with suffix, e.g. `27+`.
+	major, majorErr := strconv.Atoi(strings.TrimSuffix(i.Info.Major, "+"))
+	minor, minorErr := strconv.Atoi(strings.TrimSuffix(i.Info.Minor, "+"))
+	if majorErr != nil || minorErr != nil {
+		return nil, fmt.Errorf("no valid k8s version found in %q", path)
+	}
 	return versions.PatchSelector{
 		Major: major,
 		Minor: minor,
 		Patch: versions.AnyPoint,
 	}, nil
 }
+
+// detectNodesVersion returns the newest kubelet version of nodes stored in
+// the bundle.
+func detectNodesVersion(b bundle.Bundle) (versions.Selector, error) {
+	path := filepath.Join(b.Layout().ClusterResources(), "nodes.json")
+	list, err := bundle.LoadResourcesFromFile(b, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var newest *semver.Version
+	for i := range list.Items {
+		kubeletVersion, _, _ := unstructured.NestedString(
+			list.Items[i].Object, "status", "nodeInfo", "kubeletVersion")
+		sv, err := semver.NewVersion(kubeletVersion)
+		if err != nil {
+			continue
+		}
+		if newest == nil || sv.GreaterThan(newest) {
+			newest = sv
+		}
+	}
+	if newest == nil {
+		return nil, fmt.Errorf("no kubelet version found in %q", path)
+	}
+	return selectorFromSemver(newest), nil
+}
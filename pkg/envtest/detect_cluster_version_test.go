@@ -0,0 +1,114 @@
+package envtest
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/tools/setup-envtest/versions"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+)
+
+func newTestBundle(t *testing.T, files map[string]string) bundle.Bundle {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o644))
+	}
+	return bundle.FromFs(fs)
+}
+
+const testNodes = `{"kind": "NodeList", "apiVersion": "v1", "items": [
+  {"metadata": {"name": "a"}, "status": {"nodeInfo": {"kubeletVersion": "v1.26.3"}}},
+  {"metadata": {"name": "b"}, "status": {"nodeInfo": {"kubeletVersion": "v1.27.8-eks-8cb36c9"}}},
+  {"metadata": {"name": "c"}, "status": {"nodeInfo": {}}}
+]}`
+
+func TestDetectK8sVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected versions.Selector
+		err      string
+	}{
+		{
+			name: "cluster info",
+			files: map[string]string{
+				"cluster-info/cluster_version.json": `{"info": {"major": "1", "minor": "25"}, "string": "v1.25.5"}`,
+				"cluster-resources/nodes.json":      testNodes,
+			},
+			expected: versions.PatchSelector{Major: 1, Minor: 25, Patch: versions.AnyPoint},
+		},
+		{
+			name: "vendor version",
+			files: map[string]string{
+				"cluster-info/cluster_version.json": `{"info": {"major": "1", "minor": "27+"}, "string": "v1.27.8-eks-8cb36c9"}`,
+			},
+			expected: versions.PatchSelector{Major: 1, Minor: 27, Patch: versions.AnyPoint},
+		},
+		{
+			name: "major and minor",
+			files: map[string]string{
+				"cluster-info/cluster_version.json": `{"info": {"major": "1", "minor": "28+"}}`,
+			},
+			expected: versions.PatchSelector{Major: 1, Minor: 28, Patch: versions.AnyPoint},
+		},
+		{
+			name: "missing cluster info",
+			files: map[string]string{
+				"cluster-resources/nodes.json": testNodes,
+			},
+			expected: versions.PatchSelector{Major: 1, Minor: 27, Patch: versions.AnyPoint},
+		},
+		{
+			name: "malformed cluster info",
+			files: map[string]string{
+				"cluster-info/cluster_version.json": `{"info": {}}`,
+				"cluster-resources/nodes.json":      testNodes,
+			},
+			expected: versions.PatchSelector{Major: 1, Minor: 27, Patch: versions.AnyPoint},
+		},
+		{
+			name: "no version",
+			files: map[string]string{
+				"cluster-info/cluster_version.json": `{`,
+				"cluster-resources/nodes.json":      `{"kind": "NodeList", "apiVersion": "v1", "items": []}`,
+			},
+			err: "no kubelet version found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := DetectK8sVersion(newTestBundle(t, tt.files))
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, selector)
+		})
+	}
+}
+
+func TestNearestVersion(t *testing.T) {
+	available := []versions.Concrete{
+		{Major: 1, Minor: 24, Patch: 2},
+		{Major: 1, Minor: 26, Patch: 1},
+		{Major: 1, Minor: 28, Patch: 0},
+		{Major: 1, Minor: 30, Patch: 3},
+	}
+	minor := func(minor int) versions.PatchSelector {
+		return versions.PatchSelector{Major: 1, Minor: minor, Patch: versions.AnyPoint}
+	}
+
+	assert.Equal(t, minor(26), nearestVersion(minor(26), available))
+	assert.Equal(t, minor(24), nearestVersion(minor(19), available))
+	assert.Equal(t, minor(30), nearestVersion(minor(33), available))
+	// Newer version is preferred for the same distance.
+	assert.Equal(t, minor(28), nearestVersion(minor(27), available))
+	assert.Equal(t, minor(27), nearestVersion(minor(27), nil))
+}
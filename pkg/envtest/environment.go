@@ -53,6 +53,11 @@ func newEnvironment(binaryAssetsDirectory string, k8sVersion versions.Selector)
 	}
 }
 
+// K8sVersion returns version of the k8s environment.
+func (e *Environment) K8sVersion() versions.Selector {
+	return e.k8sVersion
+}
+
 // Start starts API server and returns admin rest config.
 func (e *Environment) Start(ctx context.Context, opts ...StartOption) (*rest.Config, error) {
 	if e.startAPIServerFn == nil {
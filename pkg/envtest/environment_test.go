@@ -3,6 +3,7 @@ package envtest
 import (
 	"context"
 	"errors"
+	"io"
 	"net/url"
 	"testing"
 
@@ -40,6 +41,18 @@ func (f *fakeStorageBackend) Allocate(_ context.Context, id string) (*StorageAll
 	return f.allocation, nil
 }
 
+func (f *fakeStorageBackend) Delete(_ context.Context, _ string) error {
+	return nil
+}
+
+func (f *fakeStorageBackend) Snapshot(_ context.Context, _ string, _ io.Writer) error {
+	return nil
+}
+
+func (f *fakeStorageBackend) Restore(_ context.Context, _ string, _ io.Reader) error {
+	return nil
+}
+
 func (f *fakeStorageBackend) Stop() error {
 	f.stopped++
 	return nil
@@ -1,6 +1,9 @@
 package envtest
 
-import "sigs.k8s.io/controller-runtime/tools/setup-envtest/env"
+import (
+	"sigs.k8s.io/controller-runtime/tools/setup-envtest/env"
+	"sigs.k8s.io/controller-runtime/tools/setup-envtest/store"
+)
 
 // Option allows to configure environment.
 type Option func(*env.Env)
@@ -16,6 +19,29 @@ func Arch(arch string) Option {
 	}
 }
 
+// AssetsDir configures directory of the envtest assets store. By default the
+// `setup-envtest` default store directory is used.
+func AssetsDir(dir string) Option {
+	return func(e *env.Env) {
+		e.Store = store.NewAt(dir)
+	}
+}
+
+// NoDownload forbids downloading envtest assets, only assets already present
+// in the store can be used.
+func NoDownload() Option {
+	return func(e *env.Env) {
+		e.NoDownload = true
+	}
+}
+
+// VerifyChecksum verifies checksums of downloaded envtest assets.
+func VerifyChecksum() Option {
+	return func(e *env.Env) {
+		e.VerifySum = true
+	}
+}
+
 // WithStorageBackend configures the storage backend used by the API server.
 func WithStorageBackend(storageBackend StorageBackend) StartOption {
 	return func(cfg *APIServerStartConfig) {
@@ -5,8 +5,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/go-logr/logr"
 	"github.com/spf13/afero"
 	"sigs.k8s.io/controller-runtime/tools/setup-envtest/env"
@@ -27,8 +31,16 @@ func Prepare(ctx context.Context, b bundle.Bundle, opts ...Option) (*Environment
 	}
 	log.Printf("Detected %q k8s version", detectedK8sVersion)
 
+	return PrepareVersion(ctx, detectedK8sVersion, opts...)
+}
+
+// PrepareVersion creates k8s environment for the provided k8s version and
+// downloads necessary envtest assets for launching it. When assets of the
+// k8s minor version are not available, the nearest available minor version
+// is used and returned by Environment.K8sVersion.
+func PrepareVersion(ctx context.Context, k8sVersion versions.Selector, opts ...Option) (*Environment, error) {
 	versionSpec := versions.Spec{
-		Selector: detectedK8sVersion,
+		Selector: k8sVersion,
 	}
 
 	envConfig, err := createEnvtest(ctx, versionSpec)
@@ -40,13 +52,49 @@ func Prepare(ctx context.Context, b bundle.Bundle, opts ...Option) (*Environment
 		o(envConfig)
 	}
 
+	// Assets are not published for all k8s versions, e.g. very old or new
+	// minor versions, in which case the nearest available minor is used.
+	if selector, ok := k8sVersion.(versions.PatchSelector); ok {
+		if nearest := resolveVersion(ctx, envConfig, selector); nearest != selector {
+			log.Printf("Assets for %q k8s version are not available, using nearest %q version", selector, nearest)
+			k8sVersion = nearest
+			envConfig.Version.Selector = nearest
+		}
+	}
+
 	binaryAssetsDirectory, err := setupEnvtest(ctx, envConfig)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Printf("Using envtest binaries from directory: %s\n", binaryAssetsDirectory)
-	return newEnvironment(binaryAssetsDirectory, detectedK8sVersion), nil
+	return newEnvironment(binaryAssetsDirectory, k8sVersion), nil
+}
+
+// PrepareBinary creates k8s environment which launches the provided
+// kube-apiserver binary instead of downloaded envtest assets. The k8s version
+// is detected by running the binary.
+func PrepareBinary(ctx context.Context, kubeAPIServerPath string) (*Environment, error) {
+	out, err := exec.CommandContext(ctx, kubeAPIServerPath, "--version").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version of kube-apiserver binary %q: %w", kubeAPIServerPath, err)
+	}
+
+	// The binary prints version as `Kubernetes v1.30.2`.
+	version := strings.TrimPrefix(strings.TrimSpace(string(out)), "Kubernetes ")
+	sv, err := semver.NewVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version of kube-apiserver binary %q: %w", kubeAPIServerPath, err)
+	}
+	k8sVersion := versions.Concrete{
+		Major: int(sv.Major()),
+		Minor: int(sv.Minor()),
+		Patch: int(sv.Patch()),
+	}
+
+	testEnv := newEnvironment(filepath.Dir(kubeAPIServerPath), k8sVersion)
+	testEnv.ControlPlane.GetAPIServer().Path = kubeAPIServerPath
+	return testEnv, nil
 }
 
 func setupEnvtest(ctx context.Context, e *env.Env) (_ string, err error) {
@@ -62,6 +110,9 @@ func setupEnvtest(ctx context.Context, e *env.Env) (_ string, err error) {
 	e.EnsureBaseDirs(ctx)
 	e.EnsureVersionIsSet(ctx)
 	if !e.ExistsAndValid() {
+		if e.NoDownload {
+			env.Exit(2, "version %s is not present in the assets store and downloads are disabled", e.Version)
+		}
 		e.Fetch(ctx)
 	}
 	out := &bytes.Buffer{}
@@ -84,9 +135,9 @@ func createEnvtest(ctx context.Context, serverVersion versions.Spec) (*env.Env,
 		Client: &remote.HTTPClient{
 			Log: logger.WithName("envtest-client"),
 		},
-		VerifySum:     false, // todo: expose?
-		ForceDownload: false, // todo: expose?
-		NoDownload:    false, // todo: expose?
+		VerifySum:     false,
+		ForceDownload: false,
+		NoDownload:    false,
 		Platform: versions.PlatformItem{
 			Platform: versions.Platform{
 				OS:   runtime.GOOS,
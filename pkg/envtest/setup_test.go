@@ -0,0 +1,76 @@
+package envtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/tools/setup-envtest/versions"
+)
+
+func TestPrepareBinaryDetectsVersion(t *testing.T) {
+	kubeAPIServer := filepath.Join(t.TempDir(), "kube-apiserver")
+	require.NoError(t, os.WriteFile(kubeAPIServer, []byte("#!/bin/sh\necho Kubernetes v1.30.2\n"), 0o755))
+
+	testEnv, err := PrepareBinary(context.Background(), kubeAPIServer)
+	require.NoError(t, err)
+	assert.Equal(t, versions.Concrete{Major: 1, Minor: 30, Patch: 2}, testEnv.K8sVersion())
+	assert.Equal(t, filepath.Dir(kubeAPIServer), testEnv.BinaryAssetsDirectory)
+	assert.Equal(t, kubeAPIServer, testEnv.ControlPlane.GetAPIServer().Path)
+}
+
+func TestPrepareBinaryRejectsInvalidVersion(t *testing.T) {
+	kubeAPIServer := filepath.Join(t.TempDir(), "kube-apiserver")
+	require.NoError(t, os.WriteFile(kubeAPIServer, []byte("#!/bin/sh\necho unknown\n"), 0o755))
+
+	_, err := PrepareBinary(context.Background(), kubeAPIServer)
+	require.ErrorContains(t, err, "invalid version of kube-apiserver binary")
+}
+
+func TestPrepareVersionWithoutDownloadRequiresLocalAssets(t *testing.T) {
+	assetsDir := t.TempDir()
+	k8sVersion := versions.PatchSelector{Major: 1, Minor: 30, Patch: versions.AnyPoint}
+
+	_, err := PrepareVersion(context.Background(), k8sVersion, AssetsDir(assetsDir), NoDownload())
+	require.ErrorContains(t, err, "no applicable on-disk versions")
+
+	// Concrete version is not looked up in the store before download.
+	_, err = PrepareVersion(context.Background(), versions.Concrete{Major: 1, Minor: 30, Patch: 2},
+		AssetsDir(assetsDir), NoDownload())
+	require.ErrorContains(t, err, "downloads are disabled")
+}
+
+func TestPrepareVersionUsesAssetsDir(t *testing.T) {
+	assetsDir := t.TempDir()
+	binDir := filepath.Join(assetsDir, "k8s", "1.30.2-"+runtime.GOOS+"-"+runtime.GOARCH)
+	require.NoError(t, os.MkdirAll(binDir, 0o755))
+	for _, name := range []string{"kube-apiserver", "etcd", "kubectl"} {
+		require.NoError(t, os.WriteFile(filepath.Join(binDir, name), nil, 0o755))
+	}
+
+	k8sVersion := versions.PatchSelector{Major: 1, Minor: 30, Patch: versions.AnyPoint}
+	testEnv, err := PrepareVersion(context.Background(), k8sVersion, AssetsDir(assetsDir), NoDownload())
+	require.NoError(t, err)
+	assert.Equal(t, binDir, testEnv.BinaryAssetsDirectory)
+}
+
+func TestPrepareVersionFallsBackToNearestVersion(t *testing.T) {
+	assetsDir := t.TempDir()
+	for _, version := range []string{"1.28.1", "1.30.2"} {
+		binDir := filepath.Join(assetsDir, "k8s", version+"-"+runtime.GOOS+"-"+runtime.GOARCH)
+		require.NoError(t, os.MkdirAll(binDir, 0o755))
+		for _, name := range []string{"kube-apiserver", "etcd", "kubectl"} {
+			require.NoError(t, os.WriteFile(filepath.Join(binDir, name), nil, 0o755))
+		}
+	}
+
+	k8sVersion := versions.PatchSelector{Major: 1, Minor: 33, Patch: versions.AnyPoint}
+	testEnv, err := PrepareVersion(context.Background(), k8sVersion, AssetsDir(assetsDir), NoDownload())
+	require.NoError(t, err)
+	assert.Equal(t, versions.PatchSelector{Major: 1, Minor: 30, Patch: versions.AnyPoint}, testEnv.K8sVersion())
+	assert.Equal(t, filepath.Join(assetsDir, "k8s", "1.30.2-"+runtime.GOOS+"-"+runtime.GOARCH), testEnv.BinaryAssetsDirectory)
+}
@@ -1,9 +1,15 @@
 package envtest
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"os"
 	"path"
 	"path/filepath"
 
@@ -23,6 +29,14 @@ type StorageAllocation struct {
 type StorageBackend interface {
 	Start(context.Context) error
 	Allocate(context.Context, string) (*StorageAllocation, error)
+	// Delete removes all data stored for the storage allocation identity.
+	Delete(context.Context, string) error
+	// Snapshot writes all data stored for the storage allocation identity.
+	Snapshot(context.Context, string, io.Writer) error
+	// Restore replaces data stored for the storage allocation identity with
+	// data written by Snapshot. The data can be restored under a different
+	// identity than they were snapshotted from.
+	Restore(context.Context, string, io.Reader) error
 	Stop() error
 }
 
@@ -36,6 +50,24 @@ func WithLocalEtcdPrefixRoot(prefixRoot string) LocalEtcdStorageOption {
 	}
 }
 
+// WithLocalEtcdDataDir configures directory in which etcd stores its data. The
+// data are kept when the backend is stopped so that they can be served again
+// without importing the bundle. By default etcd uses a temporary directory
+// which is removed on stop.
+func WithLocalEtcdDataDir(dataDir string) LocalEtcdStorageOption {
+	return func(b *localEtcdStorageBackend) {
+		b.etcd.DataDir = dataDir
+	}
+}
+
+// WithLocalEtcdBinary configures path of etcd binary. By default etcd from
+// the binary assets directory is used.
+func WithLocalEtcdBinary(path string) LocalEtcdStorageOption {
+	return func(b *localEtcdStorageBackend) {
+		b.etcd.Path = path
+	}
+}
+
 // NewLocalEtcdStorageBackend creates a local etcd storage backend.
 func NewLocalEtcdStorageBackend(binaryAssetsDirectory string, opts ...LocalEtcdStorageOption) StorageBackend {
 	backend := &localEtcdStorageBackend{
@@ -45,6 +77,7 @@ func NewLocalEtcdStorageBackend(binaryAssetsDirectory string, opts ...LocalEtcdS
 		prefixRoot:  "/registry",
 		startEtcdFn: startLocalEtcd,
 		stopEtcdFn:  stopLocalEtcd,
+		httpClient:  http.DefaultClient,
 	}
 	for _, opt := range opts {
 		opt(backend)
@@ -58,6 +91,7 @@ type localEtcdStorageBackend struct {
 	started     bool
 	startEtcdFn func(*controllerruntimeenvtest.Etcd) error
 	stopEtcdFn  func(*controllerruntimeenvtest.Etcd) error
+	httpClient  *http.Client
 }
 
 func (b *localEtcdStorageBackend) Start(_ context.Context) error {
@@ -67,6 +101,11 @@ func (b *localEtcdStorageBackend) Start(_ context.Context) error {
 	if b.startEtcdFn == nil {
 		b.startEtcdFn = startLocalEtcd
 	}
+	if b.etcd.DataDir != "" {
+		if err := os.MkdirAll(b.etcd.DataDir, 0o700); err != nil {
+			return fmt.Errorf("failed to create etcd data dir: %w", err)
+		}
+	}
 	if err := b.startEtcdFn(b.etcd); err != nil {
 		return err
 	}
@@ -92,6 +131,94 @@ func (b *localEtcdStorageBackend) Allocate(_ context.Context, bundleID string) (
 	return allocation, nil
 }
 
+// Delete removes keys under the etcd prefix of the storage allocation using
+// the etcd gRPC gateway, so that etcd client is not required.
+func (b *localEtcdStorageBackend) Delete(ctx context.Context, bundleID string) error {
+	if err := b.checkPrefixedStorage(bundleID, "deleted"); err != nil {
+		return err
+	}
+
+	key := b.storageKeyPrefix(bundleID)
+	req := map[string][]byte{
+		"key":       key,
+		"range_end": prefixRangeEnd(key),
+	}
+	if err := b.gatewayCall(ctx, "/v3/kv/deleterange", req, nil); err != nil {
+		return fmt.Errorf("failed to delete storage data: %w", err)
+	}
+	return nil
+}
+
+// checkPrefixedStorage checks that data of the storage allocation can be
+// accessed by its etcd prefix.
+func (b *localEtcdStorageBackend) checkPrefixedStorage(bundleID, operation string) error {
+	if bundleID == "" {
+		return errors.New("missing storage id")
+	}
+	if !b.started || b.etcd.URL == nil {
+		return errors.New("storage backend is not started")
+	}
+	if b.prefixRoot == "" {
+		return fmt.Errorf("storage without prefixes can't be %s per storage id", operation)
+	}
+	return nil
+}
+
+// storageKeyPrefix returns etcd key prefix of the storage allocation. The
+// trailing slash prevents matching data of IDs sharing the prefix.
+func (b *localEtcdStorageBackend) storageKeyPrefix(bundleID string) []byte {
+	return []byte(path.Join(b.prefixRoot, bundleID) + "/")
+}
+
+// gatewayCall sends JSON request to the etcd gRPC gateway endpoint and
+// decodes the response to resp, if provided. The `[]byte` values are encoded
+// as base64 strings as expected by the gateway.
+func (b *localEtcdStorageBackend) gatewayCall(ctx context.Context, endpoint string, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, b.etcd.URL.JoinPath(endpoint).String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpClient := b.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1024))
+		return fmt.Errorf("%s: %s", httpResp.Status, bytes.TrimSpace(data))
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.NewDecoder(httpResp.Body).Decode(resp)
+}
+
+// prefixRangeEnd returns the end of etcd key range that contains all keys
+// with the prefix.
+func prefixRangeEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// All bytes are 0xff, the range is open ended.
+	return []byte{0}
+}
+
 func (b *localEtcdStorageBackend) Stop() error {
 	if !b.started {
 		return nil
@@ -0,0 +1,137 @@
+package envtest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/embed"
+	"go.uber.org/zap"
+	controllerruntimeenvtest "sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// embeddedEtcdStartTimeout limits wait for the embedded etcd to serve
+// requests.
+const embeddedEtcdStartTimeout = time.Minute
+
+// NewEmbeddedEtcdStorageBackend creates a storage backend which runs etcd
+// server in the current process. It doesn't require etcd binary. By default
+// etcd data are kept in a temporary directory in tmpfs, when available, and
+// are not synced to disk. Options of the local etcd storage apply, except for
+// the etcd binary path.
+func NewEmbeddedEtcdStorageBackend(opts ...LocalEtcdStorageOption) StorageBackend {
+	embedded := &embeddedEtcd{}
+	backend := &localEtcdStorageBackend{
+		etcd:        &controllerruntimeenvtest.Etcd{},
+		prefixRoot:  "/registry",
+		startEtcdFn: embedded.start,
+		stopEtcdFn:  embedded.stop,
+		httpClient:  http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(backend)
+	}
+	return backend
+}
+
+// embeddedEtcd starts etcd server in the current process in place of the
+// etcd binary. The server serves the same API including the gRPC gateway, so
+// the storage data are accessed the same way as with the local etcd.
+type embeddedEtcd struct {
+	server *embed.Etcd
+	// tempDir is removed when the server is stopped.
+	tempDir string
+}
+
+func (e *embeddedEtcd) start(etcd *controllerruntimeenvtest.Etcd) error {
+	cfg := embed.NewConfig()
+	cfg.Dir = etcd.DataDir
+	if cfg.Dir == "" {
+		dir, err := os.MkdirTemp(tmpfsDir(), "troubleshoot-live-etcd-")
+		if err != nil {
+			return fmt.Errorf("failed to create etcd data dir: %w", err)
+		}
+		e.tempDir = dir
+		cfg.Dir = dir
+		// The data are removed on stop, syncing them to disk only slows down
+		// the import.
+		cfg.UnsafeNoFsync = true
+	}
+
+	// The gRPC gateway dials the configured client URL, so the client port
+	// must be known upfront. The peer port isn't used by a single member and
+	// is picked by the system.
+	clientAddr, err := freeLocalAddr()
+	if err != nil {
+		return errors.Join(err, e.removeTempDir())
+	}
+	client := url.URL{Scheme: "http", Host: clientAddr}
+	peer := url.URL{Scheme: "http", Host: "127.0.0.1:0"}
+	cfg.ListenClientUrls = []url.URL{client}
+	cfg.AdvertiseClientUrls = []url.URL{client}
+	cfg.ListenPeerUrls = []url.URL{peer}
+	cfg.AdvertisePeerUrls = []url.URL{peer}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	cfg.ZapLoggerBuilder = embed.NewZapLoggerBuilder(zap.NewNop())
+
+	server, err := embed.StartEtcd(cfg)
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to start embedded etcd: %w", err), e.removeTempDir())
+	}
+	select {
+	case <-server.Server.ReadyNotify():
+	case err := <-server.Err():
+		server.Close()
+		return errors.Join(fmt.Errorf("failed to start embedded etcd: %w", err), e.removeTempDir())
+	case <-time.After(embeddedEtcdStartTimeout):
+		server.Close()
+		return errors.Join(errors.New("timed out waiting for embedded etcd to start"), e.removeTempDir())
+	}
+
+	e.server = server
+	etcd.URL = &client
+	return nil
+}
+
+func (e *embeddedEtcd) stop(etcd *controllerruntimeenvtest.Etcd) error {
+	if e.server != nil {
+		e.server.Close()
+		e.server = nil
+	}
+	etcd.URL = nil
+	return e.removeTempDir()
+}
+
+func (e *embeddedEtcd) removeTempDir() error {
+	if e.tempDir == "" {
+		return nil
+	}
+	if err := os.RemoveAll(e.tempDir); err != nil {
+		return fmt.Errorf("failed to remove etcd data dir: %w", err)
+	}
+	e.tempDir = ""
+	return nil
+}
+
+// freeLocalAddr returns local address with a port that is not in use.
+func freeLocalAddr() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to find free port for etcd: %w", err)
+	}
+	addr := l.Addr().String()
+	return addr, l.Close()
+}
+
+// tmpfsDir returns directory in which the temporary etcd data are kept in
+// memory, falls back to the temporary directory.
+func tmpfsDir() string {
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		return "/dev/shm"
+	}
+	return os.TempDir()
+}
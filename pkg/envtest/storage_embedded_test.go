@@ -0,0 +1,76 @@
+package envtest
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	controllerruntimeenvtest "sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+func TestEmbeddedEtcdStorageBackendSnapshotAndRestore(t *testing.T) {
+	ctx := context.Background()
+	storage := NewEmbeddedEtcdStorageBackend()
+	_, err := storage.Allocate(ctx, "first")
+	require.Error(t, err)
+
+	require.NoError(t, storage.Start(ctx))
+	t.Cleanup(func() { _ = storage.Stop() })
+
+	allocation, err := storage.Allocate(ctx, "first")
+	require.NoError(t, err)
+	assert.Equal(t, "http", allocation.URL.Scheme)
+	assert.Equal(t, "/registry/first", allocation.APIServerArgs["etcd-prefix"])
+
+	snapshot := `{"key":"L3BvZHMvYQ==","value":"YQ=="}` + "\n" + `{"key":"L3BvZHMvYg==","value":"Yg=="}` + "\n"
+	require.NoError(t, storage.Restore(ctx, "first", strings.NewReader(snapshot)))
+	require.NoError(t, storage.Restore(ctx, "first-other", strings.NewReader(snapshot)))
+
+	out := &bytes.Buffer{}
+	require.NoError(t, storage.Snapshot(ctx, "first", out))
+	assert.Equal(t, snapshot, out.String())
+
+	require.NoError(t, storage.Delete(ctx, "first"))
+	out.Reset()
+	require.NoError(t, storage.Snapshot(ctx, "first", out))
+	assert.Empty(t, out.String())
+	out.Reset()
+	require.NoError(t, storage.Snapshot(ctx, "first-other", out))
+	assert.Equal(t, snapshot, out.String())
+}
+
+func TestEmbeddedEtcdStorageBackendDataDir(t *testing.T) {
+	ctx := context.Background()
+	dataDir := filepath.Join(t.TempDir(), "embedded")
+	snapshot := `{"key":"L3BvZHMvYQ==","value":"YQ=="}` + "\n"
+
+	storage := NewEmbeddedEtcdStorageBackend(WithLocalEtcdDataDir(dataDir))
+	require.NoError(t, storage.Start(ctx))
+	require.NoError(t, storage.Restore(ctx, "first", strings.NewReader(snapshot)))
+	require.NoError(t, storage.Stop())
+	assert.DirExists(t, dataDir)
+
+	storage = NewEmbeddedEtcdStorageBackend(WithLocalEtcdDataDir(dataDir))
+	require.NoError(t, storage.Start(ctx))
+	t.Cleanup(func() { _ = storage.Stop() })
+	out := &bytes.Buffer{}
+	require.NoError(t, storage.Snapshot(ctx, "first", out))
+	assert.Equal(t, snapshot, out.String())
+}
+
+func TestEmbeddedEtcdRemovesTemporaryDataDir(t *testing.T) {
+	embedded := &embeddedEtcd{}
+	etcd := &controllerruntimeenvtest.Etcd{}
+	require.NoError(t, embedded.start(etcd))
+	require.NotNil(t, etcd.URL)
+	dataDir := embedded.tempDir
+	assert.DirExists(t, dataDir)
+
+	require.NoError(t, embedded.stop(etcd))
+	assert.Nil(t, etcd.URL)
+	assert.NoDirExists(t, dataDir)
+}
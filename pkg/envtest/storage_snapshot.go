@@ -0,0 +1,144 @@
+package envtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// snapshotRangeLimit is the number of keys read from etcd in one request.
+	snapshotRangeLimit = 500
+
+	// Restored keys are written in transactions kept under the etcd default
+	// limits of 128 operations and 1.5MiB request size. Values are base64
+	// encoded in the request so the raw size limit is lower.
+	restoreBatchOps   = 100
+	restoreBatchBytes = 512 * 1024
+)
+
+// etcdKeyValue is etcd key-value as encoded by the gRPC gateway. Snapshot
+// writes one JSON encoded etcdKeyValue per line with the key relative to the
+// storage allocation prefix.
+type etcdKeyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value,omitempty"`
+}
+
+type etcdRangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end"`
+	Limit    int64  `json:"limit,omitempty"`
+	Revision int64  `json:"revision,omitempty"`
+}
+
+type etcdRangeResponse struct {
+	Header struct {
+		Revision int64 `json:"revision,string"`
+	} `json:"header"`
+	Kvs  []etcdKeyValue `json:"kvs"`
+	More bool           `json:"more"`
+}
+
+type etcdTxnRequest struct {
+	Success []etcdRequestOp `json:"success"`
+}
+
+type etcdRequestOp struct {
+	RequestPut etcdKeyValue `json:"request_put"`
+}
+
+// Snapshot writes keys under the etcd prefix of the storage allocation. All
+// keys are read at the same etcd revision.
+func (b *localEtcdStorageBackend) Snapshot(ctx context.Context, bundleID string, w io.Writer) error {
+	if err := b.checkPrefixedStorage(bundleID, "snapshotted"); err != nil {
+		return err
+	}
+
+	prefix := b.storageKeyPrefix(bundleID)
+	req := etcdRangeRequest{
+		Key:      prefix,
+		RangeEnd: prefixRangeEnd(prefix),
+		Limit:    snapshotRangeLimit,
+	}
+	enc := json.NewEncoder(w)
+	for {
+		resp := etcdRangeResponse{}
+		if err := b.gatewayCall(ctx, "/v3/kv/range", req, &resp); err != nil {
+			return fmt.Errorf("failed to read storage data: %w", err)
+		}
+		if req.Revision == 0 {
+			req.Revision = resp.Header.Revision
+		}
+
+		for _, kv := range resp.Kvs {
+			entry := etcdKeyValue{Key: bytes.TrimPrefix(kv.Key, prefix), Value: kv.Value}
+			if err := enc.Encode(entry); err != nil {
+				return fmt.Errorf("failed to write storage snapshot: %w", err)
+			}
+		}
+
+		if !resp.More || len(resp.Kvs) == 0 {
+			return nil
+		}
+		// Continue with the key following the last returned key.
+		req.Key = append(bytes.Clone(resp.Kvs[len(resp.Kvs)-1].Key), 0)
+	}
+}
+
+// Restore removes keys under the etcd prefix of the storage allocation and
+// writes keys from the snapshot under the prefix.
+func (b *localEtcdStorageBackend) Restore(ctx context.Context, bundleID string, r io.Reader) error {
+	if err := b.checkPrefixedStorage(bundleID, "restored"); err != nil {
+		return err
+	}
+	if err := b.Delete(ctx, bundleID); err != nil {
+		return err
+	}
+
+	prefix := b.storageKeyPrefix(bundleID)
+	batch := etcdTxnRequest{}
+	batchBytes := 0
+	flush := func() error {
+		if len(batch.Success) == 0 {
+			return nil
+		}
+		if err := b.gatewayCall(ctx, "/v3/kv/txn", batch, nil); err != nil {
+			return fmt.Errorf("failed to restore storage data: %w", err)
+		}
+		batch.Success = batch.Success[:0]
+		batchBytes = 0
+		return nil
+	}
+
+	dec := json.NewDecoder(r)
+	for {
+		entry := etcdKeyValue{}
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read storage snapshot: %w", err)
+		}
+		if len(entry.Key) == 0 {
+			return errors.New("failed to read storage snapshot: missing key")
+		}
+
+		key := append(bytes.Clone(prefix), entry.Key...)
+		size := len(key) + len(entry.Value)
+		if len(batch.Success) >= restoreBatchOps || (batchBytes > 0 && batchBytes+size > restoreBatchBytes) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		batch.Success = append(batch.Success, etcdRequestOp{
+			RequestPut: etcdKeyValue{Key: key, Value: entry.Value},
+		})
+		batchBytes += size
+	}
+	return flush()
+}
@@ -0,0 +1,136 @@
+package envtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEtcdGateway implements subset of etcd gRPC gateway used by the local
+// etcd storage backend.
+type fakeEtcdGateway struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	txns int
+}
+
+func (g *fakeEtcdGateway) sortedKeys(key, rangeEnd []byte) []string {
+	keys := []string{}
+	for k := range g.data {
+		if k >= string(key) && k < string(rangeEnd) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (g *fakeEtcdGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		req := etcdRangeRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		keys := g.sortedKeys(req.Key, req.RangeEnd)
+		more := int64(len(keys)) > req.Limit
+		if more {
+			keys = keys[:req.Limit]
+		}
+		kvs := []map[string][]byte{}
+		for _, k := range keys {
+			kvs = append(kvs, map[string][]byte{"key": []byte(k), "value": g.data[k]})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"header": map[string]string{"revision": "7"},
+			"kvs":    kvs,
+			"more":   more,
+		})
+	case "/v3/kv/deleterange":
+		req := etcdRangeRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, k := range g.sortedKeys(req.Key, req.RangeEnd) {
+			delete(g.data, k)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	case "/v3/kv/txn":
+		req := etcdTxnRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		g.txns++
+		for _, op := range req.Success {
+			g.data[string(op.RequestPut.Key)] = op.RequestPut.Value
+		}
+		_, _ = w.Write([]byte(`{"succeeded":true}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newGatewayStorageBackend(t *testing.T, gateway *fakeEtcdGateway) StorageBackend {
+	t.Helper()
+
+	server := httptest.NewServer(gateway)
+	t.Cleanup(server.Close)
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	storage := NewLocalEtcdStorageBackend("/tmp/envtest-assets")
+	local, ok := storage.(*localEtcdStorageBackend)
+	require.True(t, ok)
+	local.started = true
+	local.etcd.URL = endpoint
+	return storage
+}
+
+func TestLocalEtcdStorageBackendSnapshotAndRestore(t *testing.T) {
+	gateway := &fakeEtcdGateway{data: map[string][]byte{
+		"/registry/first0":                    []byte("other"),
+		"/registry/second/pods/default/stale": []byte("stale"),
+	}}
+	for i := 0; i < 1234; i++ {
+		gateway.data[fmt.Sprintf("/registry/first/pods/default/pod-%04d", i)] = []byte{0, byte(i)}
+	}
+	storage := newGatewayStorageBackend(t, gateway)
+
+	snapshot := &bytes.Buffer{}
+	require.NoError(t, storage.Snapshot(context.Background(), "first", snapshot))
+	assert.Equal(t, 1234, bytes.Count(snapshot.Bytes(), []byte("\n")))
+	assert.NotContains(t, snapshot.String(), "other")
+
+	require.NoError(t, storage.Restore(context.Background(), "second", snapshot))
+
+	assert.Equal(t, 13, gateway.txns)
+	assert.NotContains(t, gateway.data, "/registry/second/pods/default/stale")
+	assert.Equal(t, []byte{0, 0}, gateway.data["/registry/second/pods/default/pod-0000"])
+	assert.Len(t, gateway.sortedKeys([]byte("/registry/second/"), []byte("/registry/second0")), 1234)
+	assert.Equal(t, []byte("other"), gateway.data["/registry/first0"])
+}
+
+func TestLocalEtcdStorageBackendRestoreRejectsInvalidSnapshot(t *testing.T) {
+	storage := newGatewayStorageBackend(t, &fakeEtcdGateway{data: map[string][]byte{}})
+
+	err := storage.Restore(context.Background(), "first", strings.NewReader(`{"value":"YQ=="}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing key")
+}
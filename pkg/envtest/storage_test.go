@@ -2,7 +2,12 @@ package envtest
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -82,3 +87,65 @@ func TestLocalEtcdStorageBackendStartIsIdempotent(t *testing.T) {
 
 	assert.Equal(t, 1, started)
 }
+
+func TestLocalEtcdStorageBackendDeleteRemovesPrefix(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/kv/deleterange", r.URL.Path)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		_, _ = w.Write([]byte(`{"deleted":"3"}`))
+	}))
+	defer server.Close()
+
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	storage := NewLocalEtcdStorageBackend("/tmp/envtest-assets")
+	local, ok := storage.(*localEtcdStorageBackend)
+	require.True(t, ok)
+	local.started = true
+	local.etcd.URL = endpoint
+
+	require.NoError(t, storage.Delete(context.Background(), "first"))
+
+	key, err := base64.StdEncoding.DecodeString(got["key"])
+	require.NoError(t, err)
+	rangeEnd, err := base64.StdEncoding.DecodeString(got["range_end"])
+	require.NoError(t, err)
+	assert.Equal(t, "/registry/first/", string(key))
+	assert.Equal(t, "/registry/first0", string(rangeEnd))
+}
+
+func TestLocalEtcdStorageBackendDeleteReportsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	storage := NewLocalEtcdStorageBackend("/tmp/envtest-assets")
+	local, ok := storage.(*localEtcdStorageBackend)
+	require.True(t, ok)
+	local.started = true
+	local.etcd.URL = endpoint
+
+	err = storage.Delete(context.Background(), "first")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
+
+func TestLocalEtcdStorageBackendDataDir(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "etcd")
+	storage := NewLocalEtcdStorageBackend("/tmp/envtest-assets", WithLocalEtcdDataDir(dataDir))
+	local, ok := storage.(*localEtcdStorageBackend)
+	require.True(t, ok)
+	local.startEtcdFn = func(etcd *controllerruntimeenvtest.Etcd) error {
+		assert.Equal(t, dataDir, etcd.DataDir)
+		return nil
+	}
+
+	require.NoError(t, storage.Start(context.Background()))
+	assert.DirExists(t, dataDir)
+}
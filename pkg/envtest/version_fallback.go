@@ -0,0 +1,82 @@
+package envtest
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/tools/setup-envtest/env"
+	"sigs.k8s.io/controller-runtime/tools/setup-envtest/store"
+	"sigs.k8s.io/controller-runtime/tools/setup-envtest/versions"
+)
+
+// resolveVersion returns the selector when envtest assets of a matching
+// version are present in the store or can be downloaded. Otherwise it returns
+// selector of the nearest available minor version. Remote versions are listed
+// only when the store doesn't contain a matching version.
+func resolveVersion(ctx context.Context, e *env.Env, selector versions.PatchSelector) versions.PatchSelector {
+	ctx = logr.NewContext(ctx, e.Log)
+
+	var available []versions.Concrete
+	if err := e.Store.Initialize(ctx); err == nil {
+		items, err := e.Store.List(ctx, store.Filter{
+			Version:  versions.Spec{Selector: versions.AnySelector{}},
+			Platform: e.Platform.Platform,
+		})
+		if err == nil {
+			for _, item := range items {
+				if selector.Matches(item.Version) {
+					return selector
+				}
+				available = append(available, item.Version)
+			}
+		}
+	}
+
+	if !e.NoDownload {
+		sets, err := e.Client.ListVersions(ctx)
+		if err != nil {
+			// Without the list of remote versions the requested version is
+			// tried as is.
+			e.Log.V(1).Info("unable to list remote envtest versions", "error", err.Error())
+			return selector
+		}
+		for _, set := range sets {
+			for _, platform := range set.Platforms {
+				if e.Platform.Matches(platform.Platform) {
+					available = append(available, set.Version)
+					break
+				}
+			}
+		}
+	}
+	return nearestVersion(selector, available)
+}
+
+// nearestVersion returns the selector when any of the available versions
+// matches it. Otherwise it returns selector of the available minor version
+// closest to the selector, preferring the newer one.
+func nearestVersion(selector versions.PatchSelector, available []versions.Concrete) versions.PatchSelector {
+	if len(available) == 0 {
+		return selector
+	}
+
+	distance := func(v versions.Concrete) int {
+		d := (v.Major-selector.Major)*1000 + v.Minor - selector.Minor
+		// Newer version is preferred for the same distance.
+		if d < 0 {
+			return -2*d + 1
+		}
+		return 2 * d
+	}
+
+	nearest := available[0]
+	for _, v := range available {
+		if selector.Matches(v) {
+			return selector
+		}
+		if distance(v) < distance(nearest) {
+			nearest = v
+		}
+	}
+	return versions.PatchSelector{Major: nearest.Major, Minor: nearest.Minor, Patch: versions.AnyPoint}
+}
@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// Mode defines how objects are written to the API server.
+type Mode string
+
+const (
+	// ModeCreate creates objects that don't exist in the API server. Existing
+	// objects are left untouched.
+	ModeCreate Mode = "create"
+	// ModeApply server-side applies objects, existing objects are updated.
+	ModeApply Mode = "apply"
+)
+
+// FieldManager is the field manager of objects imported with ModeApply.
+const FieldManager = "troubleshoot-live"
+
+// WithMode configures how objects are written to the API server. Defaults to
+// ModeCreate.
+func WithMode(mode Mode) Option {
+	return func(cfg *importerConfig) {
+		cfg.mode = mode
+	}
+}
+
+func applyObjectWithRetry(
+	ctx context.Context,
+	cl dynamic.Interface,
+	gvr schema.GroupVersionResource,
+	o *unstructured.Unstructured,
+	includeStatus bool,
+	preparer ObjectPreparer,
+) (Outcome, types.UID, error) {
+	var uid types.UID
+	err := retry.OnError(importRetryBackoff, isRetryableImportErr, func() error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var err error
+		uid, err = applyObject(ctx, cl, gvr, o.DeepCopy(), includeStatus, preparer)
+		return err
+	})
+	return OutcomeApplied, uid, err
+}
+
+func applyObject(
+	ctx context.Context,
+	cl dynamic.Interface,
+	gvr schema.GroupVersionResource,
+	o *unstructured.Unstructured,
+	includeStatus bool,
+	preparer ObjectPreparer,
+) (types.UID, error) {
+	if err := preparer.Prepare(o); err != nil {
+		return "", err
+	}
+	// Server-side apply rejects objects with these fields set.
+	o.SetResourceVersion("")
+	o.SetManagedFields(nil)
+
+	applyOptions := metav1.ApplyOptions{FieldManager: FieldManager, Force: true}
+	nsClient := cl.Resource(gvr).Namespace(o.GetNamespace())
+	applied, err := nsClient.Apply(ctx, o.GetName(), o, applyOptions)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply resource: %w", err)
+	}
+
+	if _, ok := o.Object["status"]; !ok || !includeStatus {
+		return applied.GetUID(), nil
+	}
+	if _, err := nsClient.ApplyStatus(ctx, o.GetName(), o, applyOptions); err != nil {
+		return applied.GetUID(), fmt.Errorf("failed to apply status: %w", err)
+	}
+	return applied.GetUID(), nil
+}
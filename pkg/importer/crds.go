@@ -29,9 +29,12 @@ var crdGVR = schema.GroupVersionResource{
 	Resource: "customresourcedefinitions",
 }
 
+func crdsFilePath(b bundle.Bundle) string {
+	return filepath.Join(b.Layout().ClusterResources(), "custom-resource-definitions.json")
+}
+
 func loadCRDs(b bundle.Bundle) (*unstructured.UnstructuredList, error) {
-	crdsPath := filepath.Join(b.Layout().ClusterResources(), "custom-resource-definitions.json")
-	list, err := bundle.LoadResourcesFromFile(b, crdsPath)
+	list, err := bundle.LoadResourcesFromFile(b, crdsFilePath(b))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load CRDs: %w", err)
 	}
@@ -65,12 +68,21 @@ func importCRDs(
 	ctx context.Context,
 	cfg *importerConfig,
 ) error {
+	crdsPath := crdsFilePath(cfg.bundle)
+	if cfg.skipClusterScoped {
+		cfg.out.V(1).Info("Skipping cluster-scoped CRDs import")
+		if list, err := loadCRDs(cfg.bundle); err == nil {
+			for i := range list.Items {
+				cfg.report.object(crdsPath, crdGVR, &list.Items[i], OutcomeSkipped, errSkippedClusterScoped)
+			}
+		}
+		return nil
+	}
+
 	list, err := loadCRDs(cfg.bundle)
 	if err != nil {
-		cli.WarnOnErrorsFilePresence(
-			cfg.bundle, cfg.out,
-			filepath.Join(cfg.bundle.Layout().ClusterResources(), "custom-resource-definitions.json"),
-		)
+		cli.WarnOnErrorsFilePresence(cfg.bundle, cfg.out, crdsPath)
+		cfg.report.sourceError(crdsPath, err)
 		return err
 	}
 
@@ -84,6 +96,10 @@ func importCRDs(
 			prepareErrors = append(prepareErrors, err)
 			return nil
 		}
+		if !cfg.filter.keep(u) {
+			cfg.report.object(crdsPath, crdGVR, u, OutcomeSkipped, errFilteredOut)
+			return nil
+		}
 
 		gvr, includeStatus, err := cfg.gvrResolver.Detect(u)
 		cfg.out.V(5).Infof("CRD import: detected %s %q", u.GetName(), gvr)
@@ -94,18 +110,16 @@ func importCRDs(
 			cfg.out.V(1).Infof("Attempting to convert CRD %s from v1beta1 to v1", u.GetName())
 			v1beta1Extension := &apiextensionsv1beta1.CustomResourceDefinition{}
 			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, v1beta1Extension); err != nil {
-				prepareErrors = append(
-					prepareErrors,
-					fmt.Errorf("failed to convert CRD unstructured to v1beta1 extension %q: %w", u.GetName(), err),
-				)
+				err = fmt.Errorf("failed to convert CRD unstructured to v1beta1 extension %q: %w", u.GetName(), err)
+				cfg.report.object(crdsPath, crdGVR, u, OutcomeFailed, err)
+				prepareErrors = append(prepareErrors, err)
 				return nil
 			}
 			v1Extension, err := convertCRD(v1beta1Extension)
 			if err != nil {
-				prepareErrors = append(
-					prepareErrors,
-					fmt.Errorf("failed to convert CRD from v1beta1 to v1 for %q: %w", u.GetName(), err),
-				)
+				err = fmt.Errorf("failed to convert CRD from v1beta1 to v1 for %q: %w", u.GetName(), err)
+				cfg.report.object(crdsPath, crdGVR, u, OutcomeFailed, err)
+				prepareErrors = append(prepareErrors, err)
 				return nil
 			}
 
@@ -121,7 +135,7 @@ func importCRDs(
 		}
 
 		tasks = append(tasks, importTask{
-			sourcePath:    filepath.Join(cfg.bundle.Layout().ClusterResources(), "custom-resource-definitions.json"),
+			sourcePath:    crdsPath,
 			gvr:           gvr,
 			object:        u.DeepCopy(),
 			includeStatus: includeStatus,
@@ -137,7 +151,7 @@ func importCRDs(
 
 	// We use a custom worker pool to only track successfully imported CRDs
 	wp := newWorkerPool(ctx, defaultImportWorkers, func(innerCtx context.Context, task importTask) error {
-		err := importObjectWithRetry(innerCtx, cfg.dynamicClient, task.gvr, task.object, task.includeStatus, cfg.objectPreparer)
+		err := cfg.importWithProgress(innerCtx, task)
 		if err != nil {
 			cfg.out.Warnf(
 				"Failed to import %q (%s) from %q with error: %s",
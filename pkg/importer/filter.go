@@ -0,0 +1,265 @@
+package importer
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+)
+
+// errFilteredOut is the report reason of objects excluded by import filters.
+var errFilteredOut = errors.New("excluded by import filters")
+
+// WithIncludeNamespaces imports only objects from the provided namespaces.
+// Cluster-scoped objects are imported only when referenced by the imported
+// objects.
+func WithIncludeNamespaces(namespaces ...string) Option {
+	return func(cfg *importerConfig) {
+		cfg.filter.includeNamespaces = append(cfg.filter.includeNamespaces, namespaces...)
+	}
+}
+
+// WithExcludeNamespaces doesn't import objects from the provided namespaces.
+func WithExcludeNamespaces(namespaces ...string) Option {
+	return func(cfg *importerConfig) {
+		cfg.filter.excludeNamespaces = append(cfg.filter.excludeNamespaces, namespaces...)
+	}
+}
+
+// WithIncludeKinds imports only objects of the provided kinds. Kind without
+// version matches all versions. Namespaces of the imported objects are still
+// created.
+func WithIncludeKinds(kinds ...schema.GroupVersionKind) Option {
+	return func(cfg *importerConfig) {
+		cfg.filter.includeKinds = append(cfg.filter.includeKinds, kinds...)
+	}
+}
+
+// WithExcludeKinds doesn't import objects of the provided kinds. Kind without
+// version matches all versions.
+func WithExcludeKinds(kinds ...schema.GroupVersionKind) Option {
+	return func(cfg *importerConfig) {
+		cfg.filter.excludeKinds = append(cfg.filter.excludeKinds, kinds...)
+	}
+}
+
+// WithLabelSelector imports only objects matching the label selector.
+func WithLabelSelector(selector labels.Selector) Option {
+	return func(cfg *importerConfig) {
+		cfg.filter.selector = selector
+	}
+}
+
+// ParseKind parses kind in `Kind`, `Kind.group` or `Kind.version.group`
+// format, e.g. `Deployment.apps` or `Deployment.v1.apps`.
+func ParseKind(kind string) schema.GroupVersionKind {
+	gvk, gk := schema.ParseKindArg(kind)
+	if gvk != nil {
+		return *gvk
+	}
+	return gk.WithVersion("")
+}
+
+// objectFilter selects bundle objects that are imported. Cluster-scoped
+// objects referenced by the selected namespaced objects are always imported.
+type objectFilter struct {
+	includeNamespaces []string
+	excludeNamespaces []string
+	includeKinds      []schema.GroupVersionKind
+	excludeKinds      []schema.GroupVersionKind
+	selector          labels.Selector
+
+	references clusterReferences
+}
+
+// clusterReferences are cluster-scoped objects referenced by the selected
+// namespaced objects.
+type clusterReferences struct {
+	// objects by group kind and name.
+	objects map[schema.GroupKind]map[string]bool
+	// kinds of the selected objects, CRDs of the kinds are imported.
+	kinds map[schema.GroupKind]bool
+}
+
+var (
+	nodeGroupKind             = schema.GroupKind{Kind: "Node"}
+	persistentVolumeGroupKind = schema.GroupKind{Kind: "PersistentVolume"}
+	storageClassGroupKind     = schema.GroupKind{Group: "storage.k8s.io", Kind: "StorageClass"}
+	namespaceGroupKind        = schema.GroupKind{Kind: "Namespace"}
+	crdGroupKind              = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+)
+
+func (f *objectFilter) enabled() bool {
+	return len(f.includeNamespaces) > 0 || len(f.excludeNamespaces) > 0 ||
+		len(f.includeKinds) > 0 || len(f.excludeKinds) > 0 ||
+		(f.selector != nil && !f.selector.Empty())
+}
+
+func (f *objectFilter) namespaceSelected(namespace string) bool {
+	if len(f.includeNamespaces) > 0 && !slices.Contains(f.includeNamespaces, namespace) {
+		return false
+	}
+	return !slices.Contains(f.excludeNamespaces, namespace)
+}
+
+func kindMatches(kinds []schema.GroupVersionKind, gvk schema.GroupVersionKind) bool {
+	return slices.ContainsFunc(kinds, func(kind schema.GroupVersionKind) bool {
+		return kind.GroupKind() == gvk.GroupKind() && (kind.Version == "" || kind.Version == gvk.Version)
+	})
+}
+
+// objectSelected returns true when the object matches kind and label filters.
+func (f *objectFilter) objectSelected(u *unstructured.Unstructured) bool {
+	gvk := u.GroupVersionKind()
+	if len(f.includeKinds) > 0 && !kindMatches(f.includeKinds, gvk) {
+		return false
+	}
+	if kindMatches(f.excludeKinds, gvk) {
+		return false
+	}
+	return f.selector == nil || f.selector.Matches(labels.Set(u.GetLabels()))
+}
+
+// clusterObjectSelected returns true for cluster-scoped objects selected
+// directly by the filters. Cluster-scoped objects don't belong to any
+// namespace, so none is selected when namespaces are included explicitly.
+func (f *objectFilter) clusterObjectSelected(u *unstructured.Unstructured) bool {
+	return len(f.includeNamespaces) == 0 && f.objectSelected(u)
+}
+
+// keep returns true when the object should be imported. Namespaces are kept
+// when selected by namespace filters, namespaced objects when selected by all
+// filters and cluster-scoped objects when selected or referenced, unless their
+// kind is excluded.
+func (f *objectFilter) keep(u *unstructured.Unstructured) bool {
+	if !f.enabled() {
+		return true
+	}
+
+	gk := u.GroupVersionKind().GroupKind()
+	switch {
+	case gk == namespaceGroupKind:
+		return f.namespaceSelected(u.GetName())
+	case u.GetNamespace() != "":
+		return f.namespaceSelected(u.GetNamespace()) && f.objectSelected(u)
+	case gk == crdGroupKind:
+		group, _, _ := unstructured.NestedString(u.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(u.Object, "spec", "names", "kind")
+		if f.references.kinds[schema.GroupKind{Group: group, Kind: kind}] {
+			return !kindMatches(f.excludeKinds, u.GroupVersionKind())
+		}
+	case f.references.objects[gk][u.GetName()]:
+		return !kindMatches(f.excludeKinds, u.GroupVersionKind())
+	}
+	return f.clusterObjectSelected(u)
+}
+
+// keepAny returns true when any object of the list should be imported.
+func (f *objectFilter) keepAny(list *unstructured.UnstructuredList) bool {
+	for i := range list.Items {
+		if f.keep(&list.Items[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectReferences finds cluster-scoped objects referenced by the objects
+// selected by the filter: nodes of pods, persistent volumes and storage
+// classes of claims and CRDs of custom resources.
+func (f *objectFilter) collectReferences(b bundle.Bundle) error {
+	f.references = clusterReferences{
+		objects: map[schema.GroupKind]map[string]bool{},
+		kinds:   map[schema.GroupKind]bool{},
+	}
+	if !f.enabled() {
+		return nil
+	}
+
+	var volumes []unstructured.Unstructured
+	err := afero.Walk(b, b.Layout().ClusterResources(), func(path string, info fs.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return nil
+		}
+		if info.IsDir() && isClusterResourcesSkipDir(b, path) {
+			return fs.SkipDir
+		}
+		if info.IsDir() || slices.Contains(clusterResourcesSkipFiles, filepath.Base(info.Name())) || isErrorsFile(path) {
+			return nil
+		}
+
+		// Files which fail to load are reported by the import.
+		list, err := loadClusterResourcesFile(b, path)
+		if err != nil {
+			return nil
+		}
+
+		for i := range list.Items {
+			u := &list.Items[i]
+			gk := u.GroupVersionKind().GroupKind()
+			switch {
+			case gk == persistentVolumeGroupKind:
+				volumes = append(volumes, *u)
+				continue
+			case u.GetNamespace() == "":
+				if !f.clusterObjectSelected(u) {
+					continue
+				}
+			case !f.namespaceSelected(u.GetNamespace()) || !f.objectSelected(u):
+				continue
+			}
+			f.addReferences(u)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Volumes are referenced by their claims, which may be stored in the
+	// bundle after the volumes.
+	for i := range volumes {
+		pv := &volumes[i]
+		if f.references.objects[persistentVolumeGroupKind][pv.GetName()] || f.clusterObjectSelected(pv) {
+			f.addReferences(pv)
+		}
+	}
+	return nil
+}
+
+// addReferences records cluster-scoped objects referenced by the selected
+// object.
+func (f *objectFilter) addReferences(u *unstructured.Unstructured) {
+	f.references.kinds[u.GroupVersionKind().GroupKind()] = true
+
+	switch u.GroupVersionKind().GroupKind() {
+	case schema.GroupKind{Kind: "Pod"}:
+		nodeName, _, _ := unstructured.NestedString(u.Object, "spec", "nodeName")
+		f.addReference(nodeGroupKind, nodeName)
+	case schema.GroupKind{Kind: "PersistentVolumeClaim"}:
+		volumeName, _, _ := unstructured.NestedString(u.Object, "spec", "volumeName")
+		f.addReference(persistentVolumeGroupKind, volumeName)
+		storageClassName, _, _ := unstructured.NestedString(u.Object, "spec", "storageClassName")
+		f.addReference(storageClassGroupKind, storageClassName)
+	case persistentVolumeGroupKind:
+		storageClassName, _, _ := unstructured.NestedString(u.Object, "spec", "storageClassName")
+		f.addReference(storageClassGroupKind, storageClassName)
+	}
+}
+
+func (f *objectFilter) addReference(gk schema.GroupKind, name string) {
+	if name == "" {
+		return
+	}
+	if f.references.objects[gk] == nil {
+		f.references.objects[gk] = map[string]bool{}
+	}
+	f.references.objects[gk][name] = true
+}
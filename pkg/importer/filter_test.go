@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+)
+
+func testObject(apiVersion, kind, namespace, name string, fields map[string]any) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]any{}}
+	for k, v := range fields {
+		u.Object[k] = v
+	}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func TestObjectFilterKeepsReferencedClusterObjects(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"cluster-resources/pvs.json": `[
+			{"metadata": {"name": "pv-1"}, "spec": {"storageClassName": "fast"}},
+			{"metadata": {"name": "pv-2"}, "spec": {"storageClassName": "slow"}}
+		]`,
+		"cluster-resources/pods/app.json": `[
+			{"metadata": {"name": "web", "namespace": "app", "labels": {"tier": "web"}}, "spec": {"nodeName": "node-1"}},
+			{"metadata": {"name": "db", "namespace": "app", "labels": {"tier": "db"}}, "spec": {"nodeName": "node-3"}}
+		]`,
+		"cluster-resources/pods/other.json": `[
+			{"metadata": {"name": "web", "namespace": "other"}, "spec": {"nodeName": "node-2"}}
+		]`,
+		// Pod logs aren't objects even when they have a resource file extension.
+		"cluster-resources/pods/logs/app/web/app.json": `[
+			{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web", "namespace": "app"}, "spec": {"nodeName": "node-logs"}}
+		]`,
+		"cluster-resources/pvcs/app.json": `[
+			{"metadata": {"name": "data", "namespace": "app"}, "spec": {"volumeName": "pv-1"}}
+		]`,
+		"cluster-resources/custom-resources/widgets.example.com/app.json": `{"items": [
+			{"apiVersion": "example.com/v1", "kind": "Widget", "metadata": {"name": "w", "namespace": "app"}}
+		]}`,
+	}
+	fs := afero.NewMemMapFs()
+	for path, content := range files {
+		if err := afero.WriteFile(fs, path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	crd := func(group, kind string) *unstructured.Unstructured {
+		return testObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", kind+"."+group, map[string]any{
+			"spec": map[string]any{"group": group, "names": map[string]any{"kind": kind}},
+		})
+	}
+
+	tests := []struct {
+		name   string
+		filter objectFilter
+		keep   []*unstructured.Unstructured
+		skip   []*unstructured.Unstructured
+	}{
+		{
+			name:   "include namespace",
+			filter: objectFilter{includeNamespaces: []string{"app"}},
+			keep: []*unstructured.Unstructured{
+				testObject("v1", "Namespace", "", "app", nil),
+				testObject("v1", "Pod", "app", "web", nil),
+				testObject("v1", "Node", "", "node-1", nil),
+				testObject("v1", "Node", "", "node-3", nil),
+				testObject("v1", "PersistentVolume", "", "pv-1", nil),
+				testObject("storage.k8s.io/v1", "StorageClass", "", "fast", nil),
+				crd("example.com", "Widget"),
+			},
+			skip: []*unstructured.Unstructured{
+				testObject("v1", "Namespace", "", "other", nil),
+				testObject("v1", "Pod", "other", "web", nil),
+				testObject("v1", "Node", "", "node-2", nil),
+				testObject("v1", "Node", "", "node-logs", nil),
+				testObject("v1", "PersistentVolume", "", "pv-2", nil),
+				testObject("storage.k8s.io/v1", "StorageClass", "", "slow", nil),
+				crd("example.com", "Gadget"),
+			},
+		},
+		{
+			name: "label selector",
+			filter: objectFilter{
+				includeNamespaces: []string{"app"},
+				selector:          labels.SelectorFromSet(labels.Set{"tier": "web"}),
+			},
+			keep: []*unstructured.Unstructured{
+				testObject("v1", "Namespace", "", "app", nil),
+				testObject("v1", "Pod", "app", "web", map[string]any{"metadata": map[string]any{"labels": map[string]any{"tier": "web"}}}),
+				testObject("v1", "Node", "", "node-1", nil),
+			},
+			skip: []*unstructured.Unstructured{
+				testObject("v1", "Pod", "app", "db", nil),
+				testObject("v1", "Node", "", "node-3", nil),
+				testObject("v1", "PersistentVolume", "", "pv-1", nil),
+			},
+		},
+		{
+			name: "kinds",
+			filter: objectFilter{
+				excludeNamespaces: []string{"other"},
+				includeKinds:      []schema.GroupVersionKind{ParseKind("Pod"), ParseKind("Widget.v1.example.com")},
+				excludeKinds:      []schema.GroupVersionKind{ParseKind("Node")},
+			},
+			keep: []*unstructured.Unstructured{
+				testObject("v1", "Namespace", "", "app", nil),
+				testObject("v1", "Pod", "app", "web", nil),
+				testObject("example.com/v1", "Widget", "app", "w", nil),
+				crd("example.com", "Widget"),
+			},
+			skip: []*unstructured.Unstructured{
+				testObject("v1", "Namespace", "", "other", nil),
+				testObject("v1", "Node", "", "node-1", nil),
+				testObject("v1", "PersistentVolumeClaim", "app", "data", nil),
+				testObject("example.com/v2", "Widget", "app", "w", nil),
+				testObject("v1", "PersistentVolume", "", "pv-2", nil),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := tt.filter
+			if err := f.collectReferences(bundle.FromFs(fs)); err != nil {
+				t.Fatalf("failed to collect references: %v", err)
+			}
+			for _, u := range tt.keep {
+				if !f.keep(u) {
+					t.Errorf("expected %s %s to be kept", u.GetKind(), objectReference(u))
+				}
+			}
+			for _, u := range tt.skip {
+				if f.keep(u) {
+					t.Errorf("expected %s %s to be skipped", u.GetKind(), objectReference(u))
+				}
+			}
+		})
+	}
+}
+
+func TestObjectFilterDisabled(t *testing.T) {
+	t.Parallel()
+
+	f := objectFilter{selector: labels.Everything()}
+	if f.enabled() {
+		t.Fatalf("expected filter with empty selector to be disabled")
+	}
+	if !f.keep(testObject("v1", "Node", "", "node", nil)) {
+		t.Fatalf("expected all objects to be kept")
+	}
+}
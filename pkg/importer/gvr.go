@@ -14,21 +14,32 @@ import (
 type gvrCacheEntry struct {
 	gvr           schema.GroupVersionResource
 	includeStatus bool
+	namespaced    bool
 }
 
 type gvrResolver struct {
 	discoveryClient discovery.DiscoveryInterface
 	mu              sync.RWMutex
 	cache           map[string]gvrCacheEntry
+	namespaced      map[schema.GroupVersionResource]bool
 }
 
 func newGVRResolver(cl discovery.DiscoveryInterface) *gvrResolver {
 	return &gvrResolver{
 		discoveryClient: cl,
 		cache:           map[string]gvrCacheEntry{},
+		namespaced:      map[schema.GroupVersionResource]bool{},
 	}
 }
 
+// Namespaced returns true if the resource previously detected by Detect is
+// namespaced.
+func (r *gvrResolver) Namespaced(gvr schema.GroupVersionResource) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.namespaced[gvr]
+}
+
 func (r *gvrResolver) Detect(u *unstructured.Unstructured) (schema.GroupVersionResource, bool, error) {
 	cacheKey := fmt.Sprintf("%s|%s", u.GetAPIVersion(), u.GetKind())
 	r.mu.RLock()
@@ -38,30 +49,30 @@ func (r *gvrResolver) Detect(u *unstructured.Unstructured) (schema.GroupVersionR
 		return cacheEntry.gvr, cacheEntry.includeStatus, nil
 	}
 
-	gvr, includeStatus, err := detectGVRUncached(r.discoveryClient, u)
+	entry, err := detectGVRUncached(r.discoveryClient, u)
 	if err != nil {
 		return schema.GroupVersionResource{}, false, err
 	}
 
 	r.mu.Lock()
-	r.cache[cacheKey] = gvrCacheEntry{
-		gvr:           gvr,
-		includeStatus: includeStatus,
-	}
+	r.cache[cacheKey] = entry
+	r.namespaced[entry.gvr] = entry.namespaced
 	r.mu.Unlock()
 
+	gvr, includeStatus := entry.gvr, entry.includeStatus
+
 	return gvr, includeStatus, nil
 }
 
-func detectGVRUncached(cl discovery.DiscoveryInterface, u *unstructured.Unstructured) (schema.GroupVersionResource, bool, error) {
+func detectGVRUncached(cl discovery.DiscoveryInterface, u *unstructured.Unstructured) (gvrCacheEntry, error) {
 	resourcesList, err := cl.ServerResourcesForGroupVersion(u.GetAPIVersion())
 	if err != nil {
-		return schema.GroupVersionResource{}, false, err
+		return gvrCacheEntry{}, err
 	}
 
 	gv, err := schema.ParseGroupVersion(u.GetAPIVersion())
 	if err != nil {
-		return schema.GroupVersionResource{}, false, err
+		return gvrCacheEntry{}, err
 	}
 
 	hasStatus := false
@@ -73,15 +84,19 @@ func detectGVRUncached(cl discovery.DiscoveryInterface, u *unstructured.Unstruct
 
 	for _, apiResource := range resourcesList.APIResources {
 		if apiResource.Kind == u.GetKind() && !strings.Contains(apiResource.Name, "/") {
-			return schema.GroupVersionResource{
-				Group:    gv.Group,
-				Version:  gv.Version,
-				Resource: apiResource.Name,
-			}, hasStatus, nil
+			return gvrCacheEntry{
+				gvr: schema.GroupVersionResource{
+					Group:    gv.Group,
+					Version:  gv.Version,
+					Resource: apiResource.Name,
+				},
+				includeStatus: hasStatus,
+				namespaced:    apiResource.Namespaced,
+			}, nil
 		}
 	}
 
-	return schema.GroupVersionResource{}, false, fmt.Errorf("not found")
+	return gvrCacheEntry{}, fmt.Errorf("not found")
 }
 
 func gvkFromFile(path string) (schema.GroupVersionKind, error) {
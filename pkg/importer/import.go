@@ -17,6 +17,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
@@ -26,6 +27,7 @@ import (
 
 	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
 	"github.com/mhrabovcin/troubleshoot-live/pkg/cli"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
 	"github.com/mhrabovcin/troubleshoot-live/pkg/utils"
 )
 
@@ -34,6 +36,10 @@ const (
 	defaultCRDWaitTimeout = 60 * time.Second
 )
 
+// errSkippedClusterScoped is the report reason of objects skipped with
+// WithSkipClusterScoped.
+var errSkippedClusterScoped = errors.New("cluster-scoped resources are skipped")
+
 var importRetryBackoff = wait.Backoff{
 	Steps:    5,
 	Duration: 100 * time.Millisecond,
@@ -46,6 +52,10 @@ type importTask struct {
 	gvr           schema.GroupVersionResource
 	object        *unstructured.Unstructured
 	includeStatus bool
+	// waitForOwners defers import of the object until its owners are
+	// imported. Otherwise references to owners which weren't imported yet keep
+	// the bundle UIDs or are removed, see WithRemoveMissingOwnerReferences.
+	waitForOwners bool
 }
 
 type workerPool struct {
@@ -112,7 +122,7 @@ func (wp *workerPool) Wait() error {
 
 func newImportWorkerPool(ctx context.Context, cfg *importerConfig) *workerPool {
 	return newWorkerPool(ctx, defaultImportWorkers, func(innerCtx context.Context, task importTask) error {
-		err := importObjectWithRetry(innerCtx, cfg.dynamicClient, task.gvr, task.object, task.includeStatus, cfg.objectPreparer)
+		err := cfg.importWithProgress(innerCtx, task)
 		if err != nil {
 			cfg.out.Warnf(
 				"Failed to import %q (%s) from %q with error: %s",
@@ -123,8 +133,25 @@ func newImportWorkerPool(ctx context.Context, cfg *importerConfig) *workerPool {
 	})
 }
 
+// Option configures bundle import.
+type Option func(*importerConfig)
+
+// WithRewriter configures rewriter that prepares objects for import. The same
+// rewriter should be used by proxy for serving the imported objects.
+func WithRewriter(rr rewriter.ResourceRewriter) Option {
+	return func(cfg *importerConfig) {
+		cfg.objectPreparer = rewriterObjectPreparer{rewriter: rr}
+	}
+}
+
 // ImportBundle creates resources in provided API server.
-func ImportBundle(ctx context.Context, b bundle.Bundle, restCfg *rest.Config, out output.Output) error {
+func ImportBundle(
+	ctx context.Context,
+	b bundle.Bundle,
+	restCfg *rest.Config,
+	out output.Output,
+	opts ...Option,
+) error {
 	dynamicClient, err := dynamic.NewForConfig(restCfg)
 	if err != nil {
 		return err
@@ -144,21 +171,47 @@ func ImportBundle(ctx context.Context, b bundle.Bundle, restCfg *rest.Config, ou
 		gvrResolver:     newGVRResolver(discoveryClient),
 		crdWaitTimeout:  defaultCRDWaitTimeout,
 	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	switch cfg.mode {
+	case "":
+		cfg.mode = ModeCreate
+	case ModeCreate, ModeApply:
+	default:
+		return fmt.Errorf("unsupported import mode %q", cfg.mode)
+	}
+	if cfg.namespaces.enabled() {
+		cfg.objectPreparer = namespaceMappingPreparer{next: cfg.objectPreparer, mapper: cfg.namespaces}
+	}
+	if cfg.filter.enabled() {
+		cfg.out.V(1).Infof("Collecting cluster-scoped objects referenced by filtered objects...")
+		if err := cfg.filter.collectReferences(b); err != nil {
+			return fmt.Errorf("failed to collect objects referenced by filtered objects: %w", err)
+		}
+	}
 
 	var importErrors []error
-	importers := []importerFn{
-		importCRDs,
-		importNamespaces,
-		importClusterResources,
-		importCMs,
-		importSecrets,
+	importers := []struct {
+		step string
+		fn   importerFn
+	}{
+		{step: "CustomResourceDefinitions", fn: importCRDs},
+		{step: "Namespaces", fn: importNamespaces},
+		{step: "ClusterResources", fn: importClusterResources},
+		{step: "ConfigMaps", fn: importCMs},
+		{step: "Secrets", fn: importSecrets},
+		{step: "Dependents", fn: importDependents},
 	}
 
-	for _, importerFn := range importers {
-		if err := importerFn(ctx, cfg); err != nil {
+	for _, importer := range importers {
+		cfg.progress.step(importer.step)
+		if err := importer.fn(ctx, cfg); err != nil {
 			importErrors = append(importErrors, err)
 		}
 	}
+	cfg.report.fill()
 
 	if len(importErrors) > 0 {
 		out.Warn("\n!!! There were failures when importing the bundle data.")
@@ -177,32 +230,64 @@ type importerConfig struct {
 	objectPreparer  ObjectPreparer
 	gvrResolver     *gvrResolver
 	crdWaitTimeout  time.Duration
+	progress        progressTracker
+	report          reportRecorder
+	filter          objectFilter
+	relaxer         schemaRelaxer
+	owners          ownerTracker
+	// synthesizedKinds are kinds served by synthesized CRDs. Accessed only
+	// from the cluster resources walk.
+	synthesizedKinds map[schema.GroupKind]bool
+
+	mode              Mode
+	namespaces        namespaceMapper
+	skipClusterScoped bool
+	// removeMissingOwners removes owner references to owners that weren't
+	// imported.
+	removeMissingOwners bool
 }
 
 type importerFn func(context.Context, *importerConfig) error
 
+func namespacesFilePath(b bundle.Bundle) string {
+	return filepath.Join(b.Layout().ClusterResources(), "namespaces.json")
+}
+
+// loadNamespaces loads Namespace objects from the bundle.
+func loadNamespaces(b bundle.Bundle) (*unstructured.UnstructuredList, error) {
+	list, err := bundle.LoadResourcesFromFile(b, namespacesFilePath(b))
+	if err != nil {
+		return nil, err
+	}
+
+	populateGVK(list, schema.GroupVersionKind{
+		Version: "v1",
+		Kind:    "Namespace",
+	})
+	return list, nil
+}
+
 func importNamespaces(
 	ctx context.Context,
 	cfg *importerConfig,
 ) (err error) {
-	namespacesPath := filepath.Join(cfg.bundle.Layout().ClusterResources(), "namespaces.json")
-	list, loadErr := bundle.LoadResourcesFromFile(cfg.bundle, namespacesPath)
+	namespacesPath := namespacesFilePath(cfg.bundle)
+	list, loadErr := loadNamespaces(cfg.bundle)
 	if loadErr != nil {
 		cli.WarnOnErrorsFilePresence(cfg.bundle, cfg.out, namespacesPath)
+		cfg.report.sourceError(namespacesPath, loadErr)
 		return loadErr
 	}
 
-	populateGVK(list, schema.GroupVersionKind{
-		Version: "v1",
-		Kind:    "Namespace",
-	})
-
 	if len(list.Items) == 0 {
 		return nil
 	}
 
 	gvr, includeStatus, detectErr := cfg.gvrResolver.Detect(&list.Items[0])
 	if detectErr != nil {
+		for i := range list.Items {
+			cfg.report.object(namespacesPath, schema.GroupVersionResource{}, &list.Items[i], OutcomeFailed, detectErr)
+		}
 		return detectErr
 	}
 
@@ -222,6 +307,10 @@ func importNamespaces(
 			prepareErrors = append(prepareErrors, errUns)
 			continue
 		}
+		if !cfg.filter.keep(u) {
+			cfg.report.object(namespacesPath, gvr, u, OutcomeSkipped, errFilteredOut)
+			continue
+		}
 
 		addErr := wp.Add(ctx, importTask{
 			sourcePath:    namespacesPath,
@@ -242,19 +331,6 @@ func importClusterResources(
 	ctx context.Context,
 	cfg *importerConfig,
 ) (err error) {
-	skipResources := []string{
-		"custom-resource-definitions.json",
-		"pod-disruption-budgets-info.json",
-		"resources.json",
-		"groups.json",
-		"namespaces.json",
-	}
-
-	skipDirs := []string{
-		"auth-cani-list",
-		"pod-disruption-budgets",
-	}
-
 	cfg.out.V(1).Infof("Importing cluster resources concurrently...")
 	wp := newImportWorkerPool(ctx, cfg)
 
@@ -275,22 +351,19 @@ func importClusterResources(
 			return nil
 		}
 
-		if info.IsDir() && slices.Contains(skipDirs, filepath.Base(info.Name())) {
+		if info.IsDir() && isClusterResourcesSkipDir(cfg.bundle, path) {
 			return fs.SkipDir
 		}
 
-		if info.IsDir() || slices.Contains(skipResources, filepath.Base(info.Name())) {
+		if info.IsDir() || slices.Contains(clusterResourcesSkipFiles, filepath.Base(info.Name())) || isErrorsFile(path) {
 			return nil
 		}
 
-		if strings.HasSuffix(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), "-errors") {
-			return nil
-		}
-
-		list, loadErr := bundle.LoadResourcesFromFile(cfg.bundle, path)
+		list, loadErr := loadClusterResourcesFile(cfg.bundle, path)
 		if loadErr != nil {
 			cli.WarnOnErrorsFilePresence(cfg.bundle, cfg.out, path)
 			cfg.out.Errorf(utils.MaxErrorString(loadErr, 200), "Failed to load resources from file %q", path)
+			cfg.report.sourceError(path, loadErr)
 			importErrors = append(importErrors, loadErr)
 			return nil
 		}
@@ -299,31 +372,60 @@ func importClusterResources(
 			return nil
 		}
 
-		if list.Items[0].GetKind() == "" {
-			relPath, relErr := filepath.Rel(cfg.bundle.Layout().ClusterResources(), path)
-			if relErr != nil {
-				return fmt.Errorf("failed to detect kind for path %q: %w", path, relErr)
-			}
-			if gvkLocal, errFromFile := gvkFromFile(relPath); errFromFile == nil {
-				populateGVK(list, gvkLocal)
-			}
-		}
-
 		cfg.out.V(1).Infof("Loading objects from: %s ...", path)
 
 		gvr, includeStatus, detectErr := cfg.gvrResolver.Detect(&list.Items[0])
+		if detectErr != nil && !cfg.skipClusterScoped && cfg.filter.keepAny(list) {
+			// CRD of the custom resources is missing from the bundle.
+			var synthesizeErr error
+			gvr, includeStatus, synthesizeErr = importSynthesizedCRD(ctx, cfg, path, list)
+			if synthesizeErr == nil {
+				detectErr = nil
+				if cfg.synthesizedKinds == nil {
+					cfg.synthesizedKinds = map[schema.GroupKind]bool{}
+				}
+				cfg.synthesizedKinds[list.Items[0].GroupVersionKind().GroupKind()] = true
+			} else {
+				cfg.out.V(1).Infof("Unable to synthesize CRD for resources from %q: %s", path, synthesizeErr)
+			}
+		}
 		if detectErr != nil {
 			cfg.out.Errorf(detectErr, "failed to detect GVR from file %q. CRD for the resource may not be imported:", path)
+			for i := range list.Items {
+				cfg.report.object(path, schema.GroupVersionResource{}, &list.Items[i], OutcomeFailed, detectErr)
+			}
 			importErrors = append(importErrors, detectErr)
 			return nil
 		}
 
+		if cfg.skipClusterScoped && !cfg.gvrResolver.Namespaced(gvr) {
+			cfg.out.V(1).Infof("Skipping cluster-scoped %s from: %s", gvr.Resource, path)
+			for i := range list.Items {
+				cfg.report.object(path, gvr, &list.Items[i], OutcomeSkipped, errSkippedClusterScoped)
+			}
+			return nil
+		}
+
 		for i := range list.Items {
+			if !cfg.filter.keep(&list.Items[i]) {
+				cfg.report.object(path, gvr, &list.Items[i], OutcomeSkipped, errFilteredOut)
+				continue
+			}
+			object := list.Items[i].DeepCopy()
+			if cfg.synthesizedKinds[object.GroupVersionKind().GroupKind()] {
+				annotations := object.GetAnnotations()
+				if annotations == nil {
+					annotations = map[string]string{}
+				}
+				annotations[SynthesizedSchemaAnnotation] = "true"
+				object.SetAnnotations(annotations)
+			}
 			addErr := wp.Add(ctx, importTask{
 				sourcePath:    path,
 				gvr:           gvr,
-				object:        list.Items[i].DeepCopy(),
+				object:        object,
 				includeStatus: includeStatus,
+				waitForOwners: true,
 			})
 			if addErr != nil {
 				return addErr // Context cancelled, abort Walk
@@ -340,6 +442,63 @@ func importClusterResources(
 	return errors.Join(importErrors...)
 }
 
+// clusterResourcesSkipFiles are files in the cluster resources directory
+// which are imported separately or don't contain objects.
+var clusterResourcesSkipFiles = []string{
+	"custom-resource-definitions.json",
+	"pod-disruption-budgets-info.json",
+	"resources.json",
+	"groups.json",
+	"namespaces.json",
+}
+
+// clusterResourcesSkipDirs are directories in the cluster resources directory
+// which don't contain objects.
+var clusterResourcesSkipDirs = []string{
+	"auth-cani-list",
+	"pod-disruption-budgets",
+}
+
+func isClusterResourcesSkipDir(b bundle.Bundle, path string) bool {
+	return slices.Contains(clusterResourcesSkipDirs, filepath.Base(path)) || slices.Contains(podLogsDirs(b), path)
+}
+
+// podLogsDirs are directories in which troubleshoot stores pod logs, the
+// `pod-logs` directory of older bundles and `cluster-resources/pods/logs` of
+// newer ones.
+func podLogsDirs(b bundle.Bundle) []string {
+	return []string{
+		b.Layout().PodLogs(),
+		filepath.Join(b.Layout().ClusterResources(), "pods", "logs"),
+	}
+}
+
+// isErrorsFile returns true for files in which troubleshoot records errors of
+// collecting the data, e.g. `cluster-resources/pods-errors.json`.
+func isErrorsFile(path string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), "-errors")
+}
+
+// loadClusterResourcesFile loads objects from a file in the cluster resources
+// directory. Objects stored without kind get GVK based on the file path.
+func loadClusterResourcesFile(b bundle.Bundle, path string) (*unstructured.UnstructuredList, error) {
+	list, err := bundle.LoadResourcesFromFile(b, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list.Items) > 0 && list.Items[0].GetKind() == "" {
+		relPath, err := filepath.Rel(b.Layout().ClusterResources(), path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect kind for path %q: %w", path, err)
+		}
+		if gvk, err := gvkFromFile(relPath); err == nil {
+			populateGVK(list, gvk)
+		}
+	}
+	return list, nil
+}
+
 type cmOrSecretLoadFn func(afero.Fs, string) (*unstructured.Unstructured, error)
 
 func importCMOrSecrets(
@@ -348,6 +507,7 @@ func importCMOrSecrets(
 	path string,
 	loadFn cmOrSecretLoadFn,
 	gvr schema.GroupVersionResource,
+	gvk schema.GroupVersionKind,
 ) (err error) {
 	wp := newImportWorkerPool(ctx, cfg)
 
@@ -378,15 +538,23 @@ func importCMOrSecrets(
 		obj, loadErr := loadFn(cfg.bundle, path)
 		if loadErr != nil {
 			cfg.out.Errorf(utils.MaxErrorString(loadErr, 200), "Failed to import secret from %q", path)
+			cfg.report.sourceError(path, loadErr)
 			importErrors = append(importErrors, loadErr)
 			return nil
 		}
 
+		obj.SetGroupVersionKind(gvk)
+		if !cfg.filter.keep(obj) {
+			cfg.report.object(path, gvr, obj, OutcomeSkipped, errFilteredOut)
+			return nil
+		}
+
 		addErr := wp.Add(ctx, importTask{
 			sourcePath:    path,
 			gvr:           gvr,
 			object:        obj,
 			includeStatus: true,
+			waitForOwners: true,
 		})
 		if addErr != nil {
 			return addErr // Context cancelled, abort Walk
@@ -410,8 +578,12 @@ func importCMs(
 		Version:  "v1",
 		Resource: "configmaps",
 	}
+	gvk := schema.GroupVersionKind{
+		Version: "v1",
+		Kind:    "ConfigMap",
+	}
 	return importCMOrSecrets(
-		ctx, cfg, cfg.bundle.Layout().ConfigMaps(), bundle.LoadConfigMap, gvr)
+		ctx, cfg, cfg.bundle.Layout().ConfigMaps(), bundle.LoadConfigMap, gvr, gvk)
 }
 
 func importSecrets(
@@ -422,23 +594,16 @@ func importSecrets(
 		Version:  "v1",
 		Resource: "secrets",
 	}
+	gvk := schema.GroupVersionKind{
+		Version: "v1",
+		Kind:    "Secret",
+	}
 	return importCMOrSecrets(
-		ctx, cfg, cfg.bundle.Layout().Secrets(), bundle.LoadSecret, gvr)
+		ctx, cfg, cfg.bundle.Layout().Secrets(), bundle.LoadSecret, gvr, gvk)
 }
 
-func importObject(
-	ctx context.Context,
-	cl dynamic.Interface,
-	gvr schema.GroupVersionResource,
-	o *unstructured.Unstructured,
-	includeStatus bool,
-	preparer ObjectPreparer,
-) error {
-	_, err := importObjectWithResult(ctx, cl, gvr, o, includeStatus, preparer)
-	return err
-}
-
-// importObjectWithResult returns true if the object was created, or an error otherwise.
+// importObjectWithResult returns true if the object was created and UID of
+// the object in the API server, or an error otherwise.
 func importObjectWithResult(
 	ctx context.Context,
 	cl dynamic.Interface,
@@ -446,26 +611,26 @@ func importObjectWithResult(
 	o *unstructured.Unstructured,
 	includeStatus bool,
 	preparer ObjectPreparer,
-) (bool, error) {
+) (bool, types.UID, error) {
 	if err := preparer.Prepare(o); err != nil {
-		return false, err
+		return false, "", err
 	}
 
-	_, err := cl.Resource(gvr).Namespace(o.GetNamespace()).Get(ctx, o.GetName(), metav1.GetOptions{})
+	existing, err := cl.Resource(gvr).Namespace(o.GetNamespace()).Get(ctx, o.GetName(), metav1.GetOptions{})
 	if err != nil {
 		if !apierrors.IsNotFound(err) {
-			return false, fmt.Errorf("failed to get resource: %w", err)
+			return false, "", fmt.Errorf("failed to get resource: %w", err)
 		}
 		nsClient := cl.Resource(gvr).Namespace(o.GetNamespace())
 
-		created, err := createResource(ctx, o, includeStatus, nsClient)
+		created, uid, err := createResource(ctx, o, includeStatus, nsClient)
 		if err != nil {
-			return false, fmt.Errorf("failed to import resource: %w", err)
+			return created, uid, fmt.Errorf("failed to import resource: %w", err)
 		}
-		return created, nil
+		return created, uid, nil
 	}
 
-	return false, nil
+	return false, existing.GetUID(), nil
 }
 
 func asUnstructured(o runtime.Object) (*unstructured.Unstructured, error) {
@@ -476,21 +641,32 @@ func asUnstructured(o runtime.Object) (*unstructured.Unstructured, error) {
 	return u, nil
 }
 
-func createResource(ctx context.Context, u *unstructured.Unstructured, includeStatus bool, nsClient dynamic.ResourceInterface) (bool, error) {
-	_, err := nsClient.Create(ctx, u, metav1.CreateOptions{})
+func createResource(
+	ctx context.Context,
+	u *unstructured.Unstructured,
+	includeStatus bool,
+	nsClient dynamic.ResourceInterface,
+) (bool, types.UID, error) {
+	created, err := nsClient.Create(ctx, u, metav1.CreateOptions{})
 	if err != nil {
 		if apierrors.IsAlreadyExists(err) {
-			return false, nil
+			return false, "", nil
 		}
-		return false, fmt.Errorf("failed to create resource: %w", err)
+		return false, "", fmt.Errorf("failed to create resource: %w", err)
 	}
 
 	// Only import status for objects with status field
 	if _, ok := u.Object["status"]; !ok || !includeStatus {
-		return true, nil
+		return true, created.GetUID(), nil
 	}
 
-	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+	return true, created.GetUID(), importStatus(ctx, u, nsClient)
+}
+
+// importStatus sets status of the created object to the status from the
+// bundle.
+func importStatus(ctx context.Context, u *unstructured.Unstructured, nsClient dynamic.ResourceInterface) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		updated, err := nsClient.Get(ctx, u.GetName(), metav1.GetOptions{})
 		if err != nil {
 			return fmt.Errorf("failed to load created object: %w", err)
@@ -506,18 +682,13 @@ func createResource(ctx context.Context, u *unstructured.Unstructured, includeSt
 		}
 		return nil
 	})
-
-	return true, err
 }
 
 func objectReference(u *unstructured.Unstructured) string {
 	if u == nil {
 		return "<nil>"
 	}
-	if u.GetNamespace() == "" {
-		return u.GetName()
-	}
-	return fmt.Sprintf("%s/%s", u.GetNamespace(), u.GetName())
+	return objectKey(u.GetNamespace(), u.GetName())
 }
 
 func importObjectWithRetry(
@@ -527,13 +698,23 @@ func importObjectWithRetry(
 	o *unstructured.Unstructured,
 	includeStatus bool,
 	preparer ObjectPreparer,
-) error {
-	return retry.OnError(importRetryBackoff, isRetryableImportErr, func() error {
+) (Outcome, types.UID, error) {
+	outcome := OutcomeAlreadyExists
+	var uid types.UID
+	err := retry.OnError(importRetryBackoff, isRetryableImportErr, func() error {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
-		return importObject(ctx, cl, gvr, o.DeepCopy(), includeStatus, preparer)
+		created, liveUID, err := importObjectWithResult(ctx, cl, gvr, o.DeepCopy(), includeStatus, preparer)
+		if created {
+			outcome = OutcomeCreated
+		}
+		if liveUID != "" {
+			uid = liveUID
+		}
+		return err
 	})
+	return outcome, uid, err
 }
 
 func isRetryableImportErr(err error) bool {
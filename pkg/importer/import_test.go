@@ -10,7 +10,9 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
 )
 
 type stubObjectPreparer struct {
@@ -56,9 +58,14 @@ func TestImportObjectUsesInjectedPreparer(t *testing.T) {
 		},
 	}
 
-	if err := importObject(context.Background(), client, gvr, obj, false, preparer); err != nil {
+	cfg := &importerConfig{dynamicClient: client, objectPreparer: preparer}
+	outcome, _, err := cfg.importObject(context.Background(), importTask{gvr: gvr, object: obj})
+	if err != nil {
 		t.Fatalf("import failed: %v", err)
 	}
+	if outcome != OutcomeCreated {
+		t.Fatalf("expected object to be created, got %q", outcome)
+	}
 
 	if !preparer.called {
 		t.Fatalf("expected preparer to be called")
@@ -106,7 +113,8 @@ func TestImportObjectReturnsPreparerError(t *testing.T) {
 		},
 	}
 
-	err := importObject(context.Background(), client, gvr, obj, false, preparer)
+	cfg := &importerConfig{dynamicClient: client, objectPreparer: preparer}
+	_, _, err := cfg.importObject(context.Background(), importTask{gvr: gvr, object: obj})
 	if !errors.Is(err, expectedErr) {
 		t.Fatalf("expected preparer error, got: %v", err)
 	}
@@ -116,3 +124,124 @@ func TestImportObjectReturnsPreparerError(t *testing.T) {
 		t.Fatalf("expected resource to not be created when prepare fails, got err=%v", err)
 	}
 }
+
+func TestImportWithProgressReportsImportedAndFailedObjects(t *testing.T) {
+	t.Parallel()
+
+	gvr := schema.GroupVersionResource{
+		Version:  "v1",
+		Resource: "configmaps",
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			gvr: "ConfigMapList",
+		},
+	)
+
+	var reported []Progress
+	cfg := &importerConfig{
+		dynamicClient:  client,
+		objectPreparer: &stubObjectPreparer{},
+	}
+	WithProgress(func(p Progress) {
+		reported = append(reported, p)
+	})(cfg)
+
+	cfg.progress.step("ConfigMaps")
+	for _, name := range []string{"cm", "cm-2"} {
+		task := importTask{
+			gvr: gvr,
+			object: &unstructured.Unstructured{
+				Object: map[string]any{
+					"apiVersion": "v1",
+					"kind":       "ConfigMap",
+					"metadata": map[string]any{
+						"name":      name,
+						"namespace": "default",
+					},
+				},
+			},
+		}
+		if err := cfg.importWithProgress(context.Background(), task); err != nil {
+			t.Fatalf("import failed: %v", err)
+		}
+	}
+
+	cfg.objectPreparer = &stubObjectPreparer{err: errors.New("prepare failed")}
+	if err := cfg.importWithProgress(context.Background(), importTask{
+		gvr:    gvr,
+		object: &unstructured.Unstructured{Object: map[string]any{"metadata": map[string]any{"name": "cm-3"}}},
+	}); err == nil {
+		t.Fatalf("expected import error")
+	}
+
+	want := Progress{Step: "ConfigMaps", Imported: 2, Failed: 1}
+	if len(reported) != 4 || reported[3] != want {
+		t.Fatalf("expected last progress %+v out of 4 reports, got %+v", want, reported)
+	}
+}
+
+func TestApplyObjectSendsServerSideApplyPatch(t *testing.T) {
+	t.Parallel()
+
+	gvr := schema.GroupVersionResource{
+		Version:  "v1",
+		Resource: "configmaps",
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			gvr: "ConfigMapList",
+		},
+	)
+
+	var patches []clienttesting.PatchAction
+	client.PrependReactor("patch", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patches = append(patches, action.(clienttesting.PatchAction))
+		return true, &unstructured.Unstructured{}, nil
+	})
+
+	obj := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]any{
+				"name":            "cm",
+				"namespace":       "default",
+				"resourceVersion": "123",
+			},
+			"data": map[string]any{
+				"k": "v",
+			},
+		},
+	}
+
+	if _, err := applyObject(context.Background(), client, gvr, obj, true, &stubObjectPreparer{}); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	if len(patches) != 1 {
+		t.Fatalf("expected single apply patch without status, got %d", len(patches))
+	}
+	patch := patches[0]
+	if patch.GetPatchType() != types.ApplyPatchType {
+		t.Fatalf("expected apply patch, got %q", patch.GetPatchType())
+	}
+	if patch.GetNamespace() != "default" || patch.GetName() != "cm" {
+		t.Fatalf("unexpected patched object %s/%s", patch.GetNamespace(), patch.GetName())
+	}
+
+	applied := &unstructured.Unstructured{}
+	if err := applied.UnmarshalJSON(patch.GetPatch()); err != nil {
+		t.Fatalf("failed to decode patch: %v", err)
+	}
+	if applied.GetResourceVersion() != "" {
+		t.Fatalf("expected resourceVersion to be removed, got %q", applied.GetResourceVersion())
+	}
+	if applied.GetLabels()["prepared"] != "true" {
+		t.Fatalf("expected preparer to be called")
+	}
+}
@@ -0,0 +1,292 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+)
+
+// errUnknownKind is reported for files with objects of unknown kind, which
+// can't be imported.
+var errUnknownKind = errors.New("unable to determine kind of objects")
+
+// KindCount is the number of bundle objects of a kind.
+type KindCount struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Count      int    `json:"count"`
+}
+
+// NamespaceCount is the number of namespaced bundle objects in a namespace.
+// Namespaces listed in the bundle without any objects have zero count.
+type NamespaceCount struct {
+	Name    string `json:"name"`
+	Objects int    `json:"objects"`
+}
+
+// CRDInfo describes a CRD stored in the bundle.
+type CRDInfo struct {
+	Name     string   `json:"name"`
+	Group    string   `json:"group"`
+	Kind     string   `json:"kind"`
+	Scope    string   `json:"scope,omitempty"`
+	Versions []string `json:"versions"`
+}
+
+// FileInfo describes a bundle file.
+type FileInfo struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// ErrorsFile is a file in which troubleshoot recorded errors of collecting
+// the data.
+type ErrorsFile struct {
+	Path   string   `json:"path"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Inventory describes the bundle content that would be imported.
+type Inventory struct {
+	Kinds      []KindCount      `json:"kinds"`
+	Namespaces []NamespaceCount `json:"namespaces"`
+	CRDs       []CRDInfo        `json:"crds"`
+	LogFiles   []FileInfo       `json:"logFiles"`
+	ErrorFiles []ErrorsFile     `json:"errorFiles"`
+	// Errors are the files that would fail to be imported.
+	Errors []SourceError `json:"errors"`
+}
+
+type inventoryBuilder struct {
+	b          bundle.Bundle
+	inventory  *Inventory
+	kinds      map[[2]string]int
+	namespaces map[string]int
+}
+
+// Inspect builds inventory of the bundle. The bundle files are loaded the same
+// way as by ImportBundle, without an API server.
+func Inspect(b bundle.Bundle) (*Inventory, error) {
+	ib := &inventoryBuilder{
+		b: b,
+		inventory: &Inventory{
+			CRDs:       []CRDInfo{},
+			LogFiles:   []FileInfo{},
+			ErrorFiles: []ErrorsFile{},
+			Errors:     []SourceError{},
+		},
+		kinds:      map[[2]string]int{},
+		namespaces: map[string]int{},
+	}
+
+	ib.addCRDs()
+	ib.addNamespaces()
+	steps := []func() error{
+		ib.addClusterResources,
+		func() error { return ib.addCMsOrSecrets(b.Layout().ConfigMaps(), bundle.LoadConfigMap, "ConfigMap") },
+		func() error { return ib.addCMsOrSecrets(b.Layout().Secrets(), bundle.LoadSecret, "Secret") },
+		ib.addLogFiles,
+		ib.addErrorFiles,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	return ib.build(), nil
+}
+
+func (ib *inventoryBuilder) addError(path string, err error) {
+	ib.inventory.Errors = append(ib.inventory.Errors, SourceError{SourcePath: path, Reason: err.Error()})
+}
+
+func (ib *inventoryBuilder) addObject(u *unstructured.Unstructured, namespaced bool) {
+	ib.kinds[[2]string{u.GetAPIVersion(), u.GetKind()}]++
+	if namespaced && u.GetNamespace() != "" {
+		ib.namespaces[u.GetNamespace()]++
+	}
+}
+
+func (ib *inventoryBuilder) addCRDs() {
+	list, err := loadCRDs(ib.b)
+	if err != nil {
+		ib.addError(crdsFilePath(ib.b), err)
+		return
+	}
+
+	for i := range list.Items {
+		crd := &list.Items[i]
+		ib.addObject(crd, false)
+
+		info := CRDInfo{Name: crd.GetName()}
+		info.Group, _, _ = unstructured.NestedString(crd.Object, "spec", "group")
+		info.Kind, _, _ = unstructured.NestedString(crd.Object, "spec", "names", "kind")
+		info.Scope, _, _ = unstructured.NestedString(crd.Object, "spec", "scope")
+		versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+		for _, v := range versions {
+			if version, ok := v.(map[string]any); ok {
+				if name, ok := version["name"].(string); ok {
+					info.Versions = append(info.Versions, name)
+				}
+			}
+		}
+		// v1beta1 CRDs may define only a single version.
+		if version, ok, _ := unstructured.NestedString(crd.Object, "spec", "version"); ok &&
+			!slices.Contains(info.Versions, version) {
+			info.Versions = append(info.Versions, version)
+		}
+		ib.inventory.CRDs = append(ib.inventory.CRDs, info)
+	}
+}
+
+func (ib *inventoryBuilder) addNamespaces() {
+	list, err := loadNamespaces(ib.b)
+	if err != nil {
+		ib.addError(namespacesFilePath(ib.b), err)
+		return
+	}
+
+	for i := range list.Items {
+		ib.addObject(&list.Items[i], false)
+		if _, ok := ib.namespaces[list.Items[i].GetName()]; !ok {
+			ib.namespaces[list.Items[i].GetName()] = 0
+		}
+	}
+}
+
+func (ib *inventoryBuilder) addClusterResources() error {
+	return ib.walk(ib.b.Layout().ClusterResources(), func(path string, info fs.FileInfo) error {
+		if info.IsDir() && isClusterResourcesSkipDir(ib.b, path) {
+			return fs.SkipDir
+		}
+
+		if info.IsDir() || slices.Contains(clusterResourcesSkipFiles, filepath.Base(info.Name())) || isErrorsFile(path) {
+			return nil
+		}
+
+		list, err := loadClusterResourcesFile(ib.b, path)
+		if err != nil {
+			ib.addError(path, err)
+			return nil
+		}
+
+		for i := range list.Items {
+			if list.Items[i].GetKind() == "" {
+				ib.addError(path, errUnknownKind)
+				return nil
+			}
+		}
+		for i := range list.Items {
+			ib.addObject(&list.Items[i], true)
+		}
+		return nil
+	})
+}
+
+func (ib *inventoryBuilder) addCMsOrSecrets(dir string, loadFn cmOrSecretLoadFn, kind string) error {
+	return ib.walk(dir, func(path string, info fs.FileInfo) error {
+		if info.IsDir() {
+			return nil
+		}
+
+		u, err := loadFn(ib.b, path)
+		if err != nil {
+			ib.addError(path, err)
+			return nil
+		}
+		u.SetAPIVersion("v1")
+		u.SetKind(kind)
+		ib.addObject(u, true)
+		return nil
+	})
+}
+
+func (ib *inventoryBuilder) addLogFiles() error {
+	for _, dir := range podLogsDirs(ib.b) {
+		err := ib.walk(dir, func(path string, info fs.FileInfo) error {
+			if !info.IsDir() {
+				ib.inventory.LogFiles = append(ib.inventory.LogFiles, FileInfo{Path: path, Size: info.Size()})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ib *inventoryBuilder) addErrorFiles() error {
+	return ib.walk(".", func(path string, info fs.FileInfo) error {
+		if info.IsDir() || !isErrorsFile(path) {
+			return nil
+		}
+
+		errorsFile := ErrorsFile{Path: path}
+		// Troubleshoot stores the errors as JSON array of messages.
+		if data, err := afero.ReadFile(ib.b, path); err == nil {
+			_ = json.Unmarshal(data, &errorsFile.Errors)
+		}
+		ib.inventory.ErrorFiles = append(ib.inventory.ErrorFiles, errorsFile)
+		return nil
+	})
+}
+
+// walk walks the bundle directory if it exists. Files which can't be read are
+// recorded as errors.
+func (ib *inventoryBuilder) walk(dir string, fn func(string, fs.FileInfo) error) error {
+	if ok, err := afero.DirExists(ib.b, dir); err != nil || !ok {
+		return err
+	}
+
+	err := afero.Walk(ib.b, dir, func(path string, info fs.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			ib.addError(path, walkErr)
+			return nil
+		}
+		return fn(path, info)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk bundle directory %q: %w", dir, err)
+	}
+	return nil
+}
+
+func (ib *inventoryBuilder) build() *Inventory {
+	inventory := ib.inventory
+
+	inventory.Kinds = make([]KindCount, 0, len(ib.kinds))
+	for key, count := range ib.kinds {
+		inventory.Kinds = append(inventory.Kinds, KindCount{APIVersion: key[0], Kind: key[1], Count: count})
+	}
+	sort.Slice(inventory.Kinds, func(i, j int) bool {
+		a, b := inventory.Kinds[i], inventory.Kinds[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.APIVersion < b.APIVersion
+	})
+
+	inventory.Namespaces = make([]NamespaceCount, 0, len(ib.namespaces))
+	for name, count := range ib.namespaces {
+		inventory.Namespaces = append(inventory.Namespaces, NamespaceCount{Name: name, Objects: count})
+	}
+	sort.Slice(inventory.Namespaces, func(i, j int) bool {
+		return inventory.Namespaces[i].Name < inventory.Namespaces[j].Name
+	})
+
+	sort.Slice(inventory.CRDs, func(i, j int) bool {
+		return inventory.CRDs[i].Name < inventory.CRDs[j].Name
+	})
+	return inventory
+}
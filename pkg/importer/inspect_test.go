@@ -0,0 +1,102 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+)
+
+func TestInspect(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"cluster-resources/custom-resource-definitions.json": `[
+			{"metadata": {"name": "widgets.example.com"}, "spec": {"group": "example.com", "scope": "Namespaced",
+			 "names": {"kind": "Widget"}, "versions": [{"name": "v1"}, {"name": "v1beta1"}]}}
+		]`,
+		"cluster-resources/namespaces.json": `{"items": [{"metadata": {"name": "default"}}, {"metadata": {"name": "empty"}}]}`,
+		"cluster-resources/pods/default.json": `{"items": [
+			{"metadata": {"name": "a", "namespace": "default"}},
+			{"metadata": {"name": "b", "namespace": "default"}}
+		]}`,
+		"cluster-resources/pods-errors.json":                 `["failed to list pods in kube-system"]`,
+		"cluster-resources/custom-resources/widgets.json":    `[{"metadata": {"name": "w", "namespace": "default"}}]`,
+		"cluster-resources/broken.json":                      `{`,
+		"cluster-resources/auth-cani-list/default.json":      `{`,
+		"configmaps/default/cm.json":                         `{"name": "cm", "namespace": "default", "data": {"k": "v"}}`,
+		"secrets/kube-system/token.json":                     `{"name": "token", "namespace": "kube-system"}`,
+		"pod-logs/default/a-app.log":                         "line\n",
+		"cluster-resources/pods/logs/default/b/app.log":      "line\n",
+		"cluster-resources/pod-disruption-budgets-info.json": `{`,
+	}
+	fs := afero.NewMemMapFs()
+	for path, content := range files {
+		if err := afero.WriteFile(fs, path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	inventory, err := Inspect(bundle.FromFs(fs))
+	if err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+
+	wantKinds := []KindCount{
+		{APIVersion: "v1", Kind: "ConfigMap", Count: 1},
+		{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition", Count: 1},
+		{APIVersion: "v1", Kind: "Namespace", Count: 2},
+		{APIVersion: "v1", Kind: "Pod", Count: 2},
+		{APIVersion: "v1", Kind: "Secret", Count: 1},
+	}
+	if !reflect.DeepEqual(inventory.Kinds, wantKinds) {
+		t.Fatalf("unexpected kinds %+v", inventory.Kinds)
+	}
+
+	wantNamespaces := []NamespaceCount{
+		{Name: "default", Objects: 3},
+		{Name: "empty"},
+		{Name: "kube-system", Objects: 1},
+	}
+	if !reflect.DeepEqual(inventory.Namespaces, wantNamespaces) {
+		t.Fatalf("unexpected namespaces %+v", inventory.Namespaces)
+	}
+
+	wantCRDs := []CRDInfo{{
+		Name:     "widgets.example.com",
+		Group:    "example.com",
+		Kind:     "Widget",
+		Scope:    "Namespaced",
+		Versions: []string{"v1", "v1beta1"},
+	}}
+	if !reflect.DeepEqual(inventory.CRDs, wantCRDs) {
+		t.Fatalf("unexpected CRDs %+v", inventory.CRDs)
+	}
+
+	wantLogFiles := []FileInfo{
+		{Path: "pod-logs/default/a-app.log", Size: 5},
+		{Path: "cluster-resources/pods/logs/default/b/app.log", Size: 5},
+	}
+	if !reflect.DeepEqual(inventory.LogFiles, wantLogFiles) {
+		t.Fatalf("unexpected log files %+v", inventory.LogFiles)
+	}
+
+	wantErrorFiles := []ErrorsFile{{
+		Path:   "cluster-resources/pods-errors.json",
+		Errors: []string{"failed to list pods in kube-system"},
+	}}
+	if !reflect.DeepEqual(inventory.ErrorFiles, wantErrorFiles) {
+		t.Fatalf("unexpected error files %+v", inventory.ErrorFiles)
+	}
+
+	var errorPaths []string
+	for _, e := range inventory.Errors {
+		errorPaths = append(errorPaths, e.SourcePath)
+	}
+	wantErrorPaths := []string{"cluster-resources/broken.json", "cluster-resources/custom-resources/widgets.json"}
+	if !reflect.DeepEqual(errorPaths, wantErrorPaths) {
+		t.Fatalf("unexpected errors %+v", inventory.Errors)
+	}
+}
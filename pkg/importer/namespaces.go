@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// WithNamespacePrefix prefixes namespaces of imported objects. Namespaces
+// mapped with WithNamespaceMap are not prefixed.
+func WithNamespacePrefix(prefix string) Option {
+	return func(cfg *importerConfig) {
+		cfg.namespaces.prefix = prefix
+	}
+}
+
+// WithNamespaceMap imports objects from the bundle namespaces (keys) to the
+// target namespaces (values).
+func WithNamespaceMap(mapping map[string]string) Option {
+	return func(cfg *importerConfig) {
+		if cfg.namespaces.mapping == nil {
+			cfg.namespaces.mapping = map[string]string{}
+		}
+		for from, to := range mapping {
+			cfg.namespaces.mapping[from] = to
+		}
+	}
+}
+
+// WithSkipClusterScoped skips import of cluster-scoped resources, including
+// CRDs. Namespaces are still created for the imported namespaced objects.
+func WithSkipClusterScoped() Option {
+	return func(cfg *importerConfig) {
+		cfg.skipClusterScoped = true
+	}
+}
+
+type namespaceMapper struct {
+	prefix  string
+	mapping map[string]string
+}
+
+func (m namespaceMapper) enabled() bool {
+	return m.prefix != "" || len(m.mapping) > 0
+}
+
+func (m namespaceMapper) mapNamespace(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+	if mapped, ok := m.mapping[namespace]; ok {
+		return mapped
+	}
+	return m.prefix + namespace
+}
+
+// namespaceMappingPreparer moves prepared objects to mapped namespaces. The
+// Namespace objects are renamed.
+type namespaceMappingPreparer struct {
+	next   ObjectPreparer
+	mapper namespaceMapper
+}
+
+func (p namespaceMappingPreparer) Prepare(u *unstructured.Unstructured) error {
+	if err := p.next.Prepare(u); err != nil {
+		return err
+	}
+
+	if u.GetAPIVersion() == "v1" && u.GetKind() == "Namespace" {
+		u.SetName(p.mapper.mapNamespace(u.GetName()))
+		return nil
+	}
+	u.SetNamespace(p.mapper.mapNamespace(u.GetNamespace()))
+	return nil
+}
@@ -0,0 +1,80 @@
+package importer
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestNamespaceMappingPreparer(t *testing.T) {
+	t.Parallel()
+
+	preparer := namespaceMappingPreparer{
+		next: &stubObjectPreparer{},
+		mapper: namespaceMapper{
+			prefix:  "replay-",
+			mapping: map[string]string{"kube-system": "sandbox"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		object        map[string]any
+		wantName      string
+		wantNamespace string
+	}{
+		{
+			name: "namespaced object is prefixed",
+			object: map[string]any{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]any{"name": "cm", "namespace": "default"},
+			},
+			wantName:      "cm",
+			wantNamespace: "replay-default",
+		},
+		{
+			name: "mapped namespace is not prefixed",
+			object: map[string]any{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]any{"name": "cm", "namespace": "kube-system"},
+			},
+			wantName:      "cm",
+			wantNamespace: "sandbox",
+		},
+		{
+			name: "namespace is renamed",
+			object: map[string]any{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata":   map[string]any{"name": "default"},
+			},
+			wantName: "replay-default",
+		},
+		{
+			name: "cluster-scoped object is unchanged",
+			object: map[string]any{
+				"apiVersion": "v1",
+				"kind":       "Node",
+				"metadata":   map[string]any{"name": "node-1"},
+			},
+			wantName: "node-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &unstructured.Unstructured{Object: tt.object}
+			if err := preparer.Prepare(u); err != nil {
+				t.Fatalf("prepare failed: %v", err)
+			}
+			if u.GetName() != tt.wantName || u.GetNamespace() != tt.wantNamespace {
+				t.Fatalf("expected %s/%s, got %s/%s", tt.wantNamespace, tt.wantName, u.GetNamespace(), u.GetName())
+			}
+			if u.GetLabels()["prepared"] != "true" {
+				t.Fatalf("expected wrapped preparer to be called")
+			}
+		})
+	}
+}
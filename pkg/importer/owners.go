@@ -0,0 +1,199 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// WithRemoveMissingOwnerReferences removes owner references to owners that
+// weren't imported from the bundle. The garbage collector of a real cluster
+// would delete objects whose owners don't exist. API servers started by
+// `serve` don't run the garbage collector, so the references are kept there.
+func WithRemoveMissingOwnerReferences() Option {
+	return func(cfg *importerConfig) {
+		cfg.removeMissingOwners = true
+	}
+}
+
+// ownerTracker translates owner references of imported objects from UIDs of
+// the bundle objects to UIDs generated by the API server. Objects are created
+// with the translated references, so the garbage collector never sees
+// dependents with references to the bundle UIDs of imported owners.
+// Dependents are deferred until their owners are imported.
+type ownerTracker struct {
+	mu sync.Mutex
+	// liveUIDs of imported objects by their UIDs from the bundle.
+	liveUIDs map[types.UID]types.UID
+	deferred []importTask
+}
+
+func (t *ownerTracker) record(original, live types.UID) {
+	if original == "" || live == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.liveUIDs == nil {
+		t.liveUIDs = map[types.UID]types.UID{}
+	}
+	t.liveUIDs[original] = live
+}
+
+// ownersImported returns true when owners of all owner references of the
+// object were imported.
+func (t *ownerTracker) ownersImported(u *unstructured.Unstructured) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, ref := range u.GetOwnerReferences() {
+		if _, ok := t.liveUIDs[ref.UID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *ownerTracker) deferTask(task importTask) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.deferred = append(t.deferred, task)
+}
+
+func (t *ownerTracker) takeDeferred() []importTask {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	deferred := t.deferred
+	t.deferred = nil
+	return deferred
+}
+
+// translate returns copy of the object with owner references pointing to the
+// live UIDs of the owners. References to owners which weren't imported are
+// kept with the original UIDs, unless removeMissing is set. The removed
+// references are returned.
+func (t *ownerTracker) translate(
+	u *unstructured.Unstructured,
+	removeMissing bool,
+) (*unstructured.Unstructured, []metav1.OwnerReference) {
+	refs := u.GetOwnerReferences()
+	if len(refs) == 0 {
+		return u, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var kept, removed []metav1.OwnerReference
+	for _, ref := range refs {
+		live, ok := t.liveUIDs[ref.UID]
+		switch {
+		case ok:
+			ref.UID = live
+		case removeMissing:
+			removed = append(removed, ref)
+			continue
+		}
+		kept = append(kept, ref)
+	}
+
+	translated := u.DeepCopy()
+	translated.SetOwnerReferences(kept)
+	return translated, removed
+}
+
+// importDependents imports objects deferred until their owners are imported.
+// Dependents are imported in rounds, so that chains of owners (e.g. pods of
+// replica sets of deployments) are imported in order. Dependents of owners that
+// are missing from the bundle or failed to be imported are imported last.
+func importDependents(ctx context.Context, cfg *importerConfig) error {
+	var importErrors []error
+	for {
+		tasks := cfg.owners.takeDeferred()
+		if len(tasks) == 0 {
+			return errors.Join(importErrors...)
+		}
+		cfg.out.V(1).Infof("Importing %d objects which depend on imported owners...", len(tasks))
+
+		ready, waiting := splitDependents(cfg, tasks)
+		for _, task := range waiting {
+			cfg.owners.deferTask(task)
+		}
+
+		wp := newImportWorkerPool(ctx, cfg)
+		var addErr error
+		for _, task := range ready {
+			if addErr = wp.Add(ctx, task); addErr != nil {
+				break // Context cancelled
+			}
+		}
+		importErrors = append(importErrors, errorOrNil(wp.Wait())...)
+		if addErr != nil {
+			return errors.Join(append(importErrors, addErr)...)
+		}
+	}
+}
+
+// splitDependents returns tasks that can be imported in the next round. When
+// no dependent has all owners imported, the dependents whose missing owners
+// aren't waiting for import are imported without waiting for the missing
+// owners. Dependents in an owner cycle are imported without waiting as the last
+// resort.
+func splitDependents(cfg *importerConfig, tasks []importTask) ([]importTask, []importTask) {
+	var ready, waiting []importTask
+	for _, task := range tasks {
+		if cfg.owners.ownersImported(task.object) {
+			ready = append(ready, task)
+		} else {
+			waiting = append(waiting, task)
+		}
+	}
+	if len(ready) > 0 {
+		return ready, waiting
+	}
+
+	waitingUIDs := map[types.UID]bool{}
+	for _, task := range tasks {
+		waitingUIDs[task.object.GetUID()] = true
+	}
+	waiting = nil
+	for _, task := range tasks {
+		ownerWaiting := false
+		for _, ref := range task.object.GetOwnerReferences() {
+			ownerWaiting = ownerWaiting || waitingUIDs[ref.UID]
+		}
+		task.waitForOwners = false
+		if ownerWaiting {
+			waiting = append(waiting, task)
+		} else {
+			ready = append(ready, task)
+		}
+	}
+	if len(ready) == 0 {
+		return waiting, nil
+	}
+	return ready, waiting
+}
+
+// errRemovedOwnerReferences is the report reason of objects imported without
+// references to owners that weren't imported.
+func errRemovedOwnerReferences(refs []metav1.OwnerReference) error {
+	owners := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		owners = append(owners, fmt.Sprintf("%s %s", ref.Kind, ref.Name))
+	}
+	return fmt.Errorf("removed owner references to objects that weren't imported: %s", strings.Join(owners, ", "))
+}
+
+func objectKey(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
@@ -0,0 +1,151 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestOwnerTrackerTranslate(t *testing.T) {
+	t.Parallel()
+
+	tracker := &ownerTracker{}
+	tracker.record("original-rs", "live-rs")
+
+	u := &unstructured.Unstructured{}
+	u.SetOwnerReferences([]metav1.OwnerReference{
+		{Kind: "ReplicaSet", Name: "rs", UID: "original-rs"},
+		{Kind: "Node", Name: "node", UID: "missing"},
+	})
+
+	translated, removed := tracker.translate(u, true)
+	refs := translated.GetOwnerReferences()
+	if len(refs) != 1 || refs[0].UID != "live-rs" {
+		t.Fatalf("unexpected translated owner references: %+v", refs)
+	}
+	if len(removed) != 1 || removed[0].UID != "missing" {
+		t.Fatalf("unexpected removed owner references: %+v", removed)
+	}
+	if u.GetOwnerReferences()[0].UID != "original-rs" {
+		t.Fatalf("expected input owner references to stay unchanged")
+	}
+
+	translated, removed = tracker.translate(u, false)
+	refs = translated.GetOwnerReferences()
+	if len(refs) != 2 || refs[0].UID != "live-rs" || refs[1].UID != "missing" || len(removed) != 0 {
+		t.Fatalf("expected reference to missing owner to be kept, got %+v, removed %+v", refs, removed)
+	}
+	if tracker.ownersImported(u) {
+		t.Fatalf("expected object with missing owner to wait for owners")
+	}
+}
+
+func TestImportDependentsAfterOwners(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serve", func(t *testing.T) {
+		t.Parallel()
+		testImportDependentsAfterOwners(t, false)
+	})
+	t.Run("remove missing owners", func(t *testing.T) {
+		t.Parallel()
+		testImportDependentsAfterOwners(t, true)
+	})
+}
+
+func testImportDependentsAfterOwners(t *testing.T, removeMissingOwners bool) {
+	t.Helper()
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: "ConfigMapList"},
+	)
+	// Generate UIDs the way the API server would.
+	client.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		u := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		u.SetUID(types.UID("live-" + u.GetName()))
+		return false, nil, nil
+	})
+
+	report := &Report{}
+	cfg := &importerConfig{
+		dynamicClient:  client,
+		out:            output.NewDiscardingOutput(),
+		objectPreparer: defaultObjectPreparer(),
+	}
+	WithReport(report)(cfg)
+	if removeMissingOwners {
+		WithRemoveMissingOwnerReferences()(cfg)
+	}
+
+	configMap := func(name string, owners ...string) importTask {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetName(name)
+		u.SetNamespace("default")
+		u.SetUID(types.UID("original-" + name))
+		var refs []metav1.OwnerReference
+		for _, owner := range owners {
+			refs = append(refs, metav1.OwnerReference{
+				APIVersion: "v1", Kind: "ConfigMap", Name: owner, UID: types.UID("original-" + owner),
+			})
+		}
+		u.SetOwnerReferences(refs)
+		return importTask{gvr: gvr, object: u, waitForOwners: true}
+	}
+
+	// Dependents are imported before their owners, the owner of "rs" is
+	// missing from the bundle.
+	for _, task := range []importTask{configMap("pod", "rs"), configMap("rs", "missing")} {
+		if err := cfg.importWithProgress(context.Background(), task); err != nil {
+			t.Fatalf("import failed: %v", err)
+		}
+	}
+	if list, _ := client.Resource(gvr).List(context.Background(), metav1.ListOptions{}); len(list.Items) != 0 {
+		t.Fatalf("expected dependents to be deferred, got %d imported objects", len(list.Items))
+	}
+
+	if err := importDependents(context.Background(), cfg); err != nil {
+		t.Fatalf("import of dependents failed: %v", err)
+	}
+
+	get := func(name string) *unstructured.Unstructured {
+		u, err := client.Resource(gvr).Namespace("default").Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get %s: %v", name, err)
+		}
+		return u
+	}
+	rsRefs := get("rs").GetOwnerReferences()
+	switch {
+	case removeMissingOwners && len(rsRefs) != 0:
+		t.Fatalf("expected reference to missing owner to be removed, got %+v", rsRefs)
+	case !removeMissingOwners && (len(rsRefs) != 1 || rsRefs[0].UID != "original-missing"):
+		t.Fatalf("expected reference to missing owner to be kept, got %+v", rsRefs)
+	}
+	if refs := get("pod").GetOwnerReferences(); len(refs) != 1 || refs[0].UID != "live-rs" {
+		t.Fatalf("expected owner reference with live UID, got %+v", refs)
+	}
+
+	cfg.report.fill()
+	if len(report.Objects) != 2 {
+		t.Fatalf("unexpected objects %+v", report.Objects)
+	}
+	for _, o := range report.Objects {
+		removedRefs := strings.Contains(o.Reason, "removed owner references")
+		if o.Outcome != OutcomeCreated || removedRefs != (removeMissingOwners && o.Name == "rs") {
+			t.Fatalf("unexpected result %+v", o)
+		}
+	}
+}
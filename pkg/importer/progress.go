@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Progress describes progress of a bundle import.
+type Progress struct {
+	// Step is the name of the currently running import step.
+	Step string `json:"step,omitempty"`
+	// Imported is the number of objects created in the API server.
+	Imported int `json:"imported"`
+	// Failed is the number of objects that failed to be imported.
+	Failed int `json:"failed"`
+}
+
+// WithProgress configures function that is called whenever the import
+// progresses. The function may be called concurrently from import workers.
+func WithProgress(fn func(Progress)) Option {
+	return func(cfg *importerConfig) {
+		cfg.progress.fn = fn
+	}
+}
+
+type progressTracker struct {
+	mu       sync.Mutex
+	progress Progress
+	fn       func(Progress)
+}
+
+func (t *progressTracker) step(name string) {
+	t.update(func(p *Progress) {
+		p.Step = name
+	})
+}
+
+func (t *progressTracker) objectImported(err error) {
+	t.update(func(p *Progress) {
+		if err != nil {
+			p.Failed++
+		} else {
+			p.Imported++
+		}
+	})
+}
+
+func (t *progressTracker) update(fn func(*Progress)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.progress)
+	if t.fn != nil {
+		t.fn(t.progress)
+	}
+}
+
+// importWithProgress imports object of the task and records the import
+// progress and the object outcome. Objects rejected by validation of their CRD
+// are retried with relaxed CRD schema. Objects waiting for their owners are
+// deferred until the owners are imported.
+func (cfg *importerConfig) importWithProgress(ctx context.Context, task importTask) error {
+	if task.waitForOwners && !cfg.owners.ownersImported(task.object) {
+		cfg.owners.deferTask(task)
+		return nil
+	}
+
+	originalUID := task.object.GetUID()
+	var removedOwners []metav1.OwnerReference
+	task.object, removedOwners = cfg.owners.translate(task.object, cfg.removeMissingOwners)
+
+	outcome, liveUID, err := cfg.importObject(ctx, task)
+	reason := err
+	if isSchemaValidationErr(err) && !cfg.skipClusterScoped {
+		var relaxedCRD string
+		outcome, liveUID, relaxedCRD, err = cfg.importWithRelaxedSchema(ctx, task, outcome, liveUID, err)
+		reason = err
+		if err == nil {
+			reason = fmt.Errorf("imported with relaxed schema of CRD %q", relaxedCRD)
+		}
+	}
+	if err != nil {
+		outcome = OutcomeFailed
+	} else {
+		cfg.owners.record(originalUID, liveUID)
+	}
+	if len(removedOwners) > 0 {
+		cfg.out.V(1).Infof("Removed owner references of %s %s to objects that weren't imported",
+			task.object.GetKind(), objectKey(task.object.GetNamespace(), task.object.GetName()))
+		if reason == nil {
+			reason = errRemovedOwnerReferences(removedOwners)
+		}
+	}
+	cfg.progress.objectImported(err)
+	cfg.report.object(task.sourcePath, task.gvr, task.object, outcome, reason)
+	return err
+}
+
+func (cfg *importerConfig) importObject(ctx context.Context, task importTask) (Outcome, types.UID, error) {
+	importFn := importObjectWithRetry
+	if cfg.mode == ModeApply {
+		importFn = applyObjectWithRetry
+	}
+	return importFn(ctx, cfg.dynamicClient, task.gvr, task.object, task.includeStatus, cfg.objectPreparer)
+}
@@ -0,0 +1,241 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// RelaxedSchemaAnnotation marks CRDs whose schema was replaced with a schema
+// preserving unknown fields, because the bundle objects failed validation of
+// the CRD schema from the bundle.
+const RelaxedSchemaAnnotation = "troubleshoot-live/relaxed-schema"
+
+// relaxedSchemaBackoff retries objects after relaxing schema of their CRD
+// until the API server validates them with the updated schema.
+var relaxedSchemaBackoff = wait.Backoff{
+	Steps:    6,
+	Duration: 200 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.2,
+}
+
+// RelaxedCRD is a CRD whose schema was relaxed during the import.
+type RelaxedCRD struct {
+	Name string `json:"name"`
+	// Reason is the validation error of the first object rejected by the
+	// CRD schema from the bundle.
+	Reason string `json:"reason"`
+}
+
+// schemaRelaxer relaxes schema of CRDs at most once.
+type schemaRelaxer struct {
+	mu sync.Mutex
+	// relaxations by resource, including resources not served by CRDs and
+	// CRDs which failed to be relaxed.
+	relaxations map[schema.GroupVersionResource]*schemaRelaxation
+}
+
+// schemaRelaxation is relaxation of schema of a single CRD. Name and err are
+// set before done is closed.
+type schemaRelaxation struct {
+	done chan struct{}
+	// name of the relaxed CRD, empty when the resource isn't served by a CRD
+	// or the CRD failed to be relaxed.
+	name string
+	err  error
+}
+
+// importWithRelaxedSchema relaxes schema of the CRD serving the object which
+// failed validation and retries the import. The returned name of the relaxed
+// CRD is empty when the resource isn't served by a CRD.
+func (cfg *importerConfig) importWithRelaxedSchema(
+	ctx context.Context,
+	task importTask,
+	outcome Outcome,
+	uid types.UID,
+	validationErr error,
+) (Outcome, types.UID, string, error) {
+	crdName, relaxErr := cfg.relaxSchema(ctx, task.gvr, validationErr)
+	if relaxErr != nil {
+		return outcome, uid, "", errors.Join(validationErr, relaxErr)
+	}
+	if crdName == "" {
+		return outcome, uid, "", validationErr
+	}
+
+	// Object was created and only its status was rejected.
+	statusOnly := cfg.mode != ModeApply && outcome == OutcomeCreated
+	err := retry.OnError(relaxedSchemaBackoff, apierrors.IsInvalid, func() error {
+		if statusOnly {
+			o := task.object.DeepCopy()
+			if err := cfg.objectPreparer.Prepare(o); err != nil {
+				return err
+			}
+			return importStatus(ctx, o, cfg.dynamicClient.Resource(task.gvr).Namespace(o.GetNamespace()))
+		}
+
+		var err error
+		outcome, uid, err = cfg.importObject(ctx, task)
+		return err
+	})
+	return outcome, uid, crdName, err
+}
+
+// relaxSchema replaces schema of all versions of the CRD serving the resource
+// with a schema preserving unknown fields. The returned name is empty when the
+// resource isn't served by a CRD. Concurrent callers for the same resource wait
+// for the first one to relax the schema.
+func (cfg *importerConfig) relaxSchema(
+	ctx context.Context,
+	gvr schema.GroupVersionResource,
+	validationErr error,
+) (string, error) {
+	r := &cfg.relaxer
+	r.mu.Lock()
+	relaxation, ok := r.relaxations[gvr]
+	if !ok {
+		if r.relaxations == nil {
+			r.relaxations = map[schema.GroupVersionResource]*schemaRelaxation{}
+		}
+		relaxation = &schemaRelaxation{done: make(chan struct{})}
+		r.relaxations[gvr] = relaxation
+	}
+	r.mu.Unlock()
+
+	if ok {
+		select {
+		case <-relaxation.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		// Failed attempts are not repeated for the other objects.
+		if relaxation.err != nil {
+			return "", nil
+		}
+		return relaxation.name, nil
+	}
+
+	defer close(relaxation.done)
+	relaxation.name, relaxation.err = cfg.relaxCRD(ctx, gvr, validationErr)
+	if relaxation.err != nil {
+		relaxation.name = ""
+	}
+	return relaxation.name, relaxation.err
+}
+
+// relaxCRD relaxes schema of the CRD serving the resource and waits until the
+// CRD is established with the relaxed schema.
+func (cfg *importerConfig) relaxCRD(
+	ctx context.Context,
+	gvr schema.GroupVersionResource,
+	validationErr error,
+) (string, error) {
+	// Built-in resources are not served by CRDs.
+	if gvr.Group == "" {
+		return "", nil
+	}
+
+	name := gvr.Resource + "." + gvr.Group
+	crds := cfg.dynamicClient.Resource(crdGVR)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		crd, err := crds.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if err := relaxCRDSchema(crd); err != nil {
+			return err
+		}
+		_, err = crds.Update(ctx, crd, metav1.UpdateOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to relax schema of CRD %q: %w", name, err)
+	}
+
+	cfg.out.Warnf("Objects of CRD %s failed schema validation, relaxed the CRD schema to preserve unknown fields", name)
+	cfg.report.relaxedCRD(name, validationErr)
+
+	return name, waitForCRDsEstablished(ctx, cfg, []string{name})
+}
+
+// schemaValidationCauses are causes of errors of objects rejected by schema of
+// their CRD.
+var schemaValidationCauses = map[metav1.CauseType]bool{
+	metav1.CauseType(field.ErrorTypeInvalid):      true,
+	metav1.CauseType(field.ErrorTypeRequired):     true,
+	metav1.CauseType(field.ErrorTypeNotSupported): true,
+	metav1.CauseType(field.ErrorTypeDuplicate):    true,
+	metav1.CauseType(field.ErrorTypeTooLong):      true,
+	metav1.CauseType(field.ErrorTypeTooMany):      true,
+	metav1.CauseType(field.ErrorTypeTypeInvalid):  true,
+}
+
+// isSchemaValidationErr returns true if the object was rejected only because
+// of failed schema validation, which can be fixed by relaxing the schema.
+// Errors of immutable fields and other causes are not fixed by relaxing.
+func isSchemaValidationErr(err error) bool {
+	var statusErr apierrors.APIStatus
+	if !apierrors.IsInvalid(err) || !errors.As(err, &statusErr) {
+		return false
+	}
+
+	details := statusErr.Status().Details
+	if details == nil || len(details.Causes) == 0 {
+		return false
+	}
+	for _, cause := range details.Causes {
+		if !schemaValidationCauses[cause.Type] || strings.Contains(cause.Message, "field is immutable") {
+			return false
+		}
+	}
+	return true
+}
+
+// relaxCRDSchema replaces schema of all CRD versions with a schema preserving
+// unknown fields and marks the CRD with RelaxedSchemaAnnotation.
+func relaxCRDSchema(crd *unstructured.Unstructured) error {
+	versions, _, err := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	if err != nil {
+		return fmt.Errorf("failed to read CRD versions: %w", err)
+	}
+
+	for i := range versions {
+		version, ok := versions[i].(map[string]any)
+		if !ok {
+			continue
+		}
+		version["schema"] = map[string]any{
+			"openAPIV3Schema": map[string]any{
+				"type":                                 "object",
+				"x-kubernetes-preserve-unknown-fields": true,
+			},
+		}
+	}
+	if err := unstructured.SetNestedSlice(crd.Object, versions, "spec", "versions"); err != nil {
+		return fmt.Errorf("failed to set CRD versions: %w", err)
+	}
+
+	annotations := crd.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[RelaxedSchemaAnnotation] = "true"
+	crd.SetAnnotations(annotations)
+	return nil
+}
@@ -0,0 +1,286 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestImportWithProgressRelaxesRejectingCRDSchema(t *testing.T) {
+	t.Parallel()
+
+	widgetsGVR := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	crd := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]any{"name": "widgets.example.com"},
+		"spec": map[string]any{
+			"group": "example.com",
+			"versions": []any{
+				map[string]any{
+					"name": "v1",
+					"schema": map[string]any{
+						"openAPIV3Schema": map[string]any{"type": "object"},
+					},
+				},
+			},
+		},
+		"status": map[string]any{
+			"conditions": []any{
+				map[string]any{"type": "Established", "status": "True"},
+			},
+		},
+	}}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			crdGVR:     "CustomResourceDefinitionList",
+			widgetsGVR: "WidgetList",
+		},
+		crd,
+	)
+
+	// Reject widgets until the CRD schema is relaxed.
+	schemaRelaxed := false
+	client.PrependReactor("update", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		updated := action.(k8stesting.UpdateAction).GetObject().(*unstructured.Unstructured)
+		schemaRelaxed = updated.GetAnnotations()[RelaxedSchemaAnnotation] == "true"
+		return false, nil, nil
+	})
+	client.PrependReactor("create", "widgets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if schemaRelaxed {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewInvalid(
+			schema.GroupKind{Group: "example.com", Kind: "Widget"},
+			"w",
+			field.ErrorList{field.Invalid(field.NewPath("spec", "size"), "large", "must be integer")},
+		)
+	})
+
+	report := &Report{}
+	cfg := &importerConfig{
+		dynamicClient:  client,
+		out:            output.NewDiscardingOutput(),
+		objectPreparer: &stubObjectPreparer{},
+		crdWaitTimeout: time.Second,
+	}
+	WithReport(report)(cfg)
+
+	widget := func(name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("example.com/v1")
+		u.SetKind("Widget")
+		u.SetName(name)
+		u.SetNamespace("default")
+		return u
+	}
+
+	for _, name := range []string{"w1", "w2"} {
+		task := importTask{sourcePath: "custom-resources/widgets.example.com/default.json", gvr: widgetsGVR, object: widget(name)}
+		if err := cfg.importWithProgress(context.Background(), task); err != nil {
+			t.Fatalf("import of %s failed: %v", name, err)
+		}
+	}
+
+	relaxed, err := client.Resource(crdGVR).Get(context.Background(), "widgets.example.com", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get CRD: %v", err)
+	}
+	versions, _, _ := unstructured.NestedSlice(relaxed.Object, "spec", "versions")
+	preserve, _, _ := unstructured.NestedBool(versions[0].(map[string]any),
+		"schema", "openAPIV3Schema", "x-kubernetes-preserve-unknown-fields")
+	if !preserve {
+		t.Fatalf("expected relaxed CRD schema, got %v", versions)
+	}
+
+	cfg.report.fill()
+	if len(report.RelaxedCRDs) != 1 || report.RelaxedCRDs[0].Name != "widgets.example.com" ||
+		!strings.Contains(report.RelaxedCRDs[0].Reason, "must be integer") {
+		t.Fatalf("unexpected relaxed CRDs %+v", report.RelaxedCRDs)
+	}
+	if len(report.Objects) != 2 {
+		t.Fatalf("unexpected objects %+v", report.Objects)
+	}
+	if first := report.Objects[0]; first.Outcome != OutcomeCreated || !strings.Contains(first.Reason, "relaxed schema") {
+		t.Fatalf("unexpected result of rejected object %+v", first)
+	}
+	if second := report.Objects[1]; second.Outcome != OutcomeCreated || second.Reason != "" {
+		t.Fatalf("unexpected result of object imported after relaxation %+v", second)
+	}
+}
+
+func TestImportWithProgressRelaxesSchemaRejectingStatus(t *testing.T) {
+	t.Parallel()
+
+	widgetsGVR := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	crd := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]any{"name": "widgets.example.com"},
+		"spec": map[string]any{
+			"group":    "example.com",
+			"versions": []any{map[string]any{"name": "v1"}},
+		},
+		"status": map[string]any{
+			"conditions": []any{
+				map[string]any{"type": "Established", "status": "True"},
+			},
+		},
+	}}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			crdGVR:     "CustomResourceDefinitionList",
+			widgetsGVR: "WidgetList",
+		},
+		crd,
+	)
+
+	// Reject status of widgets until the CRD schema is relaxed.
+	schemaRelaxed := false
+	creates := 0
+	client.PrependReactor("update", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		updated := action.(k8stesting.UpdateAction).GetObject().(*unstructured.Unstructured)
+		schemaRelaxed = updated.GetAnnotations()[RelaxedSchemaAnnotation] == "true"
+		return false, nil, nil
+	})
+	client.PrependReactor("create", "widgets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		creates++
+		return false, nil, nil
+	})
+	client.PrependReactor("update", "widgets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "status" || schemaRelaxed {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewInvalid(
+			schema.GroupKind{Group: "example.com", Kind: "Widget"},
+			"w",
+			field.ErrorList{field.Required(field.NewPath("status", "phase"), "")},
+		)
+	})
+
+	report := &Report{}
+	cfg := &importerConfig{
+		dynamicClient:  client,
+		out:            output.NewDiscardingOutput(),
+		objectPreparer: &stubObjectPreparer{},
+		crdWaitTimeout: time.Second,
+	}
+	WithReport(report)(cfg)
+
+	widget := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]any{"name": "w", "namespace": "default"},
+		"status":     map[string]any{"ready": true},
+	}}
+	task := importTask{gvr: widgetsGVR, object: widget, includeStatus: true}
+	if err := cfg.importWithProgress(context.Background(), task); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if creates != 1 {
+		t.Fatalf("expected object to be created once, got %d creates", creates)
+	}
+
+	imported, err := client.Resource(widgetsGVR).Namespace("default").Get(context.Background(), "w", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get widget: %v", err)
+	}
+	if ready, _, _ := unstructured.NestedBool(imported.Object, "status", "ready"); !ready {
+		t.Fatalf("expected status to be imported, got %v", imported.Object["status"])
+	}
+
+	cfg.report.fill()
+	if len(report.Objects) != 1 || report.Objects[0].Outcome != OutcomeCreated {
+		t.Fatalf("unexpected objects %+v", report.Objects)
+	}
+}
+
+func TestIsSchemaValidationErr(t *testing.T) {
+	t.Parallel()
+
+	invalid := func(errs ...*field.Error) error {
+		return apierrors.NewInvalid(schema.GroupKind{Group: "example.com", Kind: "Widget"}, "w", errs)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "schema validation",
+			err: invalid(
+				field.Invalid(field.NewPath("spec", "size"), "large", "must be integer"),
+				field.Required(field.NewPath("spec", "name"), ""),
+			),
+			want: true,
+		},
+		{
+			name: "wrapped schema validation",
+			err:  fmt.Errorf("failed to import resource: %w", invalid(field.NotSupported(field.NewPath("spec", "mode"), "x", []string{"y"}))),
+			want: true,
+		},
+		{
+			name: "immutable field",
+			err:  invalid(field.Invalid(field.NewPath("spec", "selector"), "x", "field is immutable")),
+		},
+		{
+			name: "forbidden field",
+			err:  invalid(field.Forbidden(field.NewPath("spec", "size"), "may not be set")),
+		},
+		{
+			name: "without causes",
+			err:  invalid(),
+		},
+		{
+			name: "not invalid",
+			err:  apierrors.NewBadRequest("invalid"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isSchemaValidationErr(tt.err); got != tt.want {
+				t.Fatalf("expected %t for %v, got %t", tt.want, tt.err, got)
+			}
+		})
+	}
+}
+
+func TestRelaxSchemaIgnoresResourcesWithoutCRD(t *testing.T) {
+	t.Parallel()
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{crdGVR: "CustomResourceDefinitionList"},
+	)
+	cfg := &importerConfig{dynamicClient: client, out: output.NewDiscardingOutput()}
+
+	for _, gvr := range []schema.GroupVersionResource{
+		{Version: "v1", Resource: "pods"},
+		{Group: "apps", Version: "v1", Resource: "deployments"},
+	} {
+		name, err := cfg.relaxSchema(context.Background(), gvr, apierrors.NewBadRequest("invalid"))
+		if err != nil || name != "" {
+			t.Fatalf("expected no relaxed CRD for %s, got %q: %v", gvr, name, err)
+		}
+	}
+}
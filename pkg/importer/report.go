@@ -0,0 +1,258 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Outcome is the result of importing a single object from the bundle.
+type Outcome string
+
+const (
+	// OutcomeCreated means the object was created in the API server.
+	OutcomeCreated Outcome = "created"
+	// OutcomeAlreadyExists means the object existed in the API server and
+	// was left untouched.
+	OutcomeAlreadyExists Outcome = "already-exists"
+	// OutcomeApplied means the object was server-side applied with ModeApply.
+	OutcomeApplied Outcome = "applied"
+	// OutcomeSkipped means the object was not imported on purpose, e.g.
+	// cluster-scoped objects with WithSkipClusterScoped.
+	OutcomeSkipped Outcome = "skipped"
+	// OutcomeFailed means the object couldn't be imported.
+	OutcomeFailed Outcome = "failed"
+)
+
+// ObjectResult is the import outcome of a single object from the bundle.
+// Namespace and name are the values from the bundle.
+type ObjectResult struct {
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version"`
+	// Resource is empty when the resource of the object kind couldn't be
+	// detected, e.g. because its CRD is missing.
+	Resource   string  `json:"resource,omitempty"`
+	Kind       string  `json:"kind,omitempty"`
+	Namespace  string  `json:"namespace,omitempty"`
+	Name       string  `json:"name"`
+	SourcePath string  `json:"sourcePath"`
+	Outcome    Outcome `json:"outcome"`
+	// Reason is the error message of failed objects, the reason why the
+	// object was skipped or a note about how the object was imported, e.g.
+	// for CRDs with synthesized schema.
+	Reason string `json:"reason,omitempty"`
+}
+
+// SourceError is an error of a bundle file from which no objects could be
+// loaded.
+type SourceError struct {
+	SourcePath string `json:"sourcePath"`
+	Reason     string `json:"reason"`
+}
+
+// ResourceSummary counts outcomes of objects of a single resource.
+type ResourceSummary struct {
+	// Resource is the fully qualified resource name, e.g. deployments.v1.apps.
+	Resource      string `json:"resource"`
+	Created       int    `json:"created"`
+	AlreadyExists int    `json:"alreadyExists"`
+	Applied       int    `json:"applied"`
+	Skipped       int    `json:"skipped"`
+	Failed        int    `json:"failed"`
+}
+
+// Report describes outcomes of all objects processed by the bundle import.
+type Report struct {
+	Summary []ResourceSummary `json:"summary"`
+	Objects []ObjectResult    `json:"objects"`
+	Errors  []SourceError     `json:"errors,omitempty"`
+	// RelaxedCRDs are CRDs whose schema rejected the bundle objects and was
+	// relaxed to preserve unknown fields.
+	RelaxedCRDs []RelaxedCRD `json:"relaxedCRDs,omitempty"`
+}
+
+// WithReport configures report that is filled with outcomes of the imported
+// objects when ImportBundle returns.
+func WithReport(report *Report) Option {
+	return func(cfg *importerConfig) {
+		cfg.report.target = report
+	}
+}
+
+// resourceName returns fully qualified resource name of the object. Kind is
+// used when the resource is unknown.
+func (o ObjectResult) resourceName() string {
+	name := o.Resource
+	if name == "" {
+		name = o.Kind
+	}
+	name += "." + o.Version
+	if o.Group != "" {
+		name += "." + o.Group
+	}
+	return name
+}
+
+func (s *ResourceSummary) add(outcome Outcome) {
+	switch outcome {
+	case OutcomeCreated:
+		s.Created++
+	case OutcomeAlreadyExists:
+		s.AlreadyExists++
+	case OutcomeApplied:
+		s.Applied++
+	case OutcomeSkipped:
+		s.Skipped++
+	case OutcomeFailed:
+		s.Failed++
+	}
+}
+
+// WriteSummary writes the summary as a table with a row for every resource
+// and the total counts.
+func (r *Report) WriteSummary(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tCREATED\tALREADY-EXISTS\tAPPLIED\tSKIPPED\tFAILED")
+
+	total := ResourceSummary{Resource: "TOTAL"}
+	for _, s := range r.Summary {
+		writeSummaryRow(tw, s)
+		total.Created += s.Created
+		total.AlreadyExists += s.AlreadyExists
+		total.Applied += s.Applied
+		total.Skipped += s.Skipped
+		total.Failed += s.Failed
+	}
+	writeSummaryRow(tw, total)
+	return tw.Flush()
+}
+
+func writeSummaryRow(w io.Writer, s ResourceSummary) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\n",
+		s.Resource, s.Created, s.AlreadyExists, s.Applied, s.Skipped, s.Failed)
+}
+
+// WriteFile writes the report to the file as YAML when the file has .yaml or
+// .yml extension, as JSON otherwise.
+func (r *Report) WriteFile(path string) error {
+	var (
+		data []byte
+		err  error
+	)
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(r)
+	default:
+		data, err = json.MarshalIndent(r, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode import report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write import report: %w", err)
+	}
+	return nil
+}
+
+// reportRecorder collects results of objects from concurrent import workers.
+type reportRecorder struct {
+	mu          sync.Mutex
+	objects     []ObjectResult
+	errors      []SourceError
+	relaxedCRDs []RelaxedCRD
+	target      *Report
+}
+
+func (r *reportRecorder) object(
+	sourcePath string,
+	gvr schema.GroupVersionResource,
+	o *unstructured.Unstructured,
+	outcome Outcome,
+	reason error,
+) {
+	result := ObjectResult{
+		Group:      gvr.Group,
+		Version:    gvr.Version,
+		Resource:   gvr.Resource,
+		Kind:       o.GetKind(),
+		Namespace:  o.GetNamespace(),
+		Name:       o.GetName(),
+		SourcePath: sourcePath,
+		Outcome:    outcome,
+	}
+	if gvr.Resource == "" {
+		gv := o.GroupVersionKind().GroupVersion()
+		result.Group, result.Version = gv.Group, gv.Version
+	}
+	if reason != nil {
+		result.Reason = reason.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.objects = append(r.objects, result)
+}
+
+func (r *reportRecorder) sourceError(sourcePath string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, SourceError{SourcePath: sourcePath, Reason: err.Error()})
+}
+
+func (r *reportRecorder) relaxedCRD(name string, reason error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.relaxedCRDs = append(r.relaxedCRDs, RelaxedCRD{Name: name, Reason: reason.Error()})
+}
+
+// fill writes sorted results and their summary to the target report.
+func (r *reportRecorder) fill() {
+	if r.target == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	objects := append([]ObjectResult(nil), r.objects...)
+	sort.SliceStable(objects, func(i, j int) bool {
+		a, b := objects[i], objects[j]
+		if a.resourceName() != b.resourceName() {
+			return a.resourceName() < b.resourceName()
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	var summary []ResourceSummary
+	for _, o := range objects {
+		name := o.resourceName()
+		if len(summary) == 0 || summary[len(summary)-1].Resource != name {
+			summary = append(summary, ResourceSummary{Resource: name})
+		}
+		summary[len(summary)-1].add(o.Outcome)
+	}
+
+	sourceErrors := append([]SourceError(nil), r.errors...)
+	sort.Slice(sourceErrors, func(i, j int) bool {
+		return sourceErrors[i].SourcePath < sourceErrors[j].SourcePath
+	})
+
+	relaxedCRDs := append([]RelaxedCRD(nil), r.relaxedCRDs...)
+	sort.Slice(relaxedCRDs, func(i, j int) bool {
+		return relaxedCRDs[i].Name < relaxedCRDs[j].Name
+	})
+
+	*r.target = Report{Summary: summary, Objects: objects, Errors: sourceErrors, RelaxedCRDs: relaxedCRDs}
+}
@@ -0,0 +1,167 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func TestImportWithProgressRecordsObjectOutcomes(t *testing.T) {
+	t.Parallel()
+
+	gvr := schema.GroupVersionResource{
+		Version:  "v1",
+		Resource: "configmaps",
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			gvr: "ConfigMapList",
+		},
+	)
+
+	report := &Report{}
+	cfg := &importerConfig{
+		dynamicClient:  client,
+		objectPreparer: &stubObjectPreparer{},
+	}
+	WithReport(report)(cfg)
+
+	configMap := func(name string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]any{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]any{
+					"name":      name,
+					"namespace": "default",
+				},
+			},
+		}
+	}
+
+	for _, name := range []string{"cm-b", "cm-a", "cm-a"} {
+		task := importTask{sourcePath: "configmaps/default.json", gvr: gvr, object: configMap(name)}
+		if err := cfg.importWithProgress(context.Background(), task); err != nil {
+			t.Fatalf("import failed: %v", err)
+		}
+	}
+
+	cfg.objectPreparer = &stubObjectPreparer{err: errors.New("prepare failed")}
+	task := importTask{sourcePath: "configmaps/default.json", gvr: gvr, object: configMap("cm-c")}
+	if err := cfg.importWithProgress(context.Background(), task); err == nil {
+		t.Fatalf("expected import error")
+	}
+
+	unknown := &unstructured.Unstructured{}
+	unknown.SetAPIVersion("example.com/v1")
+	unknown.SetKind("Widget")
+	unknown.SetName("w")
+	cfg.report.object("cluster-resources/widgets.json", schema.GroupVersionResource{}, unknown,
+		OutcomeSkipped, errSkippedClusterScoped)
+	cfg.report.sourceError("configmaps/broken.json", errors.New("invalid json"))
+
+	cfg.report.fill()
+
+	var outcomes []string
+	for _, o := range report.Objects {
+		outcomes = append(outcomes, o.resourceName()+" "+o.Name+" "+string(o.Outcome))
+	}
+	want := []string{
+		"Widget.v1.example.com w skipped",
+		"configmaps.v1 cm-a created",
+		"configmaps.v1 cm-a already-exists",
+		"configmaps.v1 cm-b created",
+		"configmaps.v1 cm-c failed",
+	}
+	if strings.Join(outcomes, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected outcomes:\n%s", strings.Join(outcomes, "\n"))
+	}
+	if failed := report.Objects[4]; failed.Reason != "prepare failed" || failed.SourcePath != "configmaps/default.json" {
+		t.Fatalf("unexpected failed object result %+v", failed)
+	}
+
+	wantSummary := []ResourceSummary{
+		{Resource: "Widget.v1.example.com", Skipped: 1},
+		{Resource: "configmaps.v1", Created: 2, AlreadyExists: 1, Failed: 1},
+	}
+	if len(report.Summary) != 2 || report.Summary[0] != wantSummary[0] || report.Summary[1] != wantSummary[1] {
+		t.Fatalf("unexpected summary %+v", report.Summary)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].SourcePath != "configmaps/broken.json" {
+		t.Fatalf("unexpected source errors %+v", report.Errors)
+	}
+}
+
+func TestReportWriteSummary(t *testing.T) {
+	t.Parallel()
+
+	report := &Report{Summary: []ResourceSummary{
+		{Resource: "configmaps.v1", Created: 2, Failed: 1},
+		{Resource: "deployments.v1.apps", AlreadyExists: 3},
+	}}
+
+	var b strings.Builder
+	if err := report.WriteSummary(&b); err != nil {
+		t.Fatalf("failed to write summary: %v", err)
+	}
+
+	want := `RESOURCE             CREATED  ALREADY-EXISTS  APPLIED  SKIPPED  FAILED
+configmaps.v1        2        0               0        0        1
+deployments.v1.apps  0        3               0        0        0
+TOTAL                2        3               0        0        1
+`
+	if b.String() != want {
+		t.Fatalf("unexpected summary table:\n%s", b.String())
+	}
+}
+
+func TestReportWriteFile(t *testing.T) {
+	t.Parallel()
+
+	report := &Report{
+		Summary: []ResourceSummary{{Resource: "configmaps.v1", Created: 1}},
+		Objects: []ObjectResult{{
+			Version:    "v1",
+			Resource:   "configmaps",
+			Kind:       "ConfigMap",
+			Namespace:  "default",
+			Name:       "cm",
+			SourcePath: "configmaps/default.json",
+			Outcome:    OutcomeCreated,
+		}},
+	}
+
+	for _, name := range []string{"report.json", "report.yaml"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := report.WriteFile(path); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if strings.HasSuffix(name, ".yaml") && !strings.Contains(string(data), "outcome: created") {
+			t.Fatalf("expected YAML report, got:\n%s", data)
+		}
+
+		decoded := &Report{}
+		if err := yaml.Unmarshal(data, decoded); err != nil {
+			t.Fatalf("failed to decode %s: %v", name, err)
+		}
+		if len(decoded.Objects) != 1 || decoded.Objects[0] != report.Objects[0] {
+			t.Fatalf("unexpected decoded report %+v", decoded)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// SynthesizedSchemaAnnotation marks CRDs synthesized for custom resources
+// whose CRD is missing from the bundle, and the custom resources served by
+// them. The schema of such CRD preserves all fields of the objects.
+const SynthesizedSchemaAnnotation = "troubleshoot-live/synthesized-schema"
+
+// customResourcesDir is the cluster-resources directory in which troubleshoot
+// stores custom resources, in `<plural>.<group>.json` files for cluster-scoped
+// resources and `<plural>.<group>/<namespace>.json` files for namespaced ones.
+const customResourcesDir = "custom-resources"
+
+// errSynthesizedSchema is the report reason of the synthesized CRDs.
+var errSynthesizedSchema = errors.New("CRD is missing from the bundle, synthesized schema preserving unknown fields")
+
+// synthesizeCRD returns a permissive CRD for the custom resources loaded from
+// the bundle file. Group, version and kind are taken from the objects, plural
+// and scope from the file path when possible.
+func synthesizeCRD(path string, list *unstructured.UnstructuredList) (*apiextensionsv1.CustomResourceDefinition, error) {
+	if len(list.Items) == 0 {
+		return nil, errors.New("no objects to synthesize CRD for")
+	}
+
+	gvk := list.Items[0].GroupVersionKind()
+	// Groups of built-in resources don't contain dot, while CRD groups must.
+	if gvk.Kind == "" || !strings.Contains(gvk.Group, ".") {
+		return nil, fmt.Errorf("%s is not a custom resource", gvk)
+	}
+
+	plural, namespacedPath := customResourceFromPath(path, gvk.Group)
+	if plural == "" {
+		guessed, _ := meta.UnsafeGuessKindToResource(gvk)
+		plural = guessed.Resource
+	}
+
+	scope := apiextensionsv1.ClusterScoped
+	if namespacedPath {
+		scope = apiextensionsv1.NamespaceScoped
+	}
+	for i := range list.Items {
+		if list.Items[i].GetNamespace() != "" {
+			scope = apiextensionsv1.NamespaceScoped
+		}
+	}
+
+	preserveUnknownFields := true
+	return &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        plural + "." + gvk.Group,
+			Annotations: map[string]string{SynthesizedSchemaAnnotation: "true"},
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: gvk.Group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:   plural,
+				Singular: strings.ToLower(gvk.Kind),
+				Kind:     gvk.Kind,
+				ListKind: gvk.Kind + "List",
+			},
+			Scope: scope,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:    gvk.Version,
+				Served:  true,
+				Storage: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type:                   "object",
+						XPreserveUnknownFields: &preserveUnknownFields,
+					},
+				},
+				Subresources: &apiextensionsv1.CustomResourceSubresources{
+					Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+				},
+			}},
+		},
+	}, nil
+}
+
+// customResourceFromPath returns plural of the resource stored in the custom
+// resources file and whether the file is in the namespaced layout. Plural is
+// empty when it can't be determined from the path.
+func customResourceFromPath(path, group string) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i := range parts {
+		if parts[i] != customResourcesDir || i+1 >= len(parts) {
+			continue
+		}
+
+		name := parts[i+1]
+		namespaced := i+2 < len(parts)
+		if !namespaced {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		plural, found := strings.CutSuffix(name, "."+group)
+		if !found || plural == "" {
+			return "", namespaced
+		}
+		return plural, namespaced
+	}
+	return "", false
+}
+
+// importSynthesizedCRD creates a synthesized CRD for the custom resources of
+// the bundle file and returns their resource once the API server serves it.
+func importSynthesizedCRD(
+	ctx context.Context,
+	cfg *importerConfig,
+	path string,
+	list *unstructured.UnstructuredList,
+) (schema.GroupVersionResource, bool, error) {
+	crd, err := synthesizeCRD(path, list)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, err
+	}
+
+	uMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(crd)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("failed to convert synthesized CRD %q: %w", crd.Name, err)
+	}
+	u := &unstructured.Unstructured{Object: uMap}
+
+	_, err = cfg.dynamicClient.Resource(crdGVR).Create(ctx, u, metav1.CreateOptions{})
+	if err != nil {
+		// CRD of the same name exists and doesn't serve the objects.
+		cfg.report.object(path, crdGVR, u, OutcomeFailed, err)
+		return schema.GroupVersionResource{}, false, fmt.Errorf("failed to create synthesized CRD %q: %w", crd.Name, err)
+	}
+	cfg.out.Warnf("CRD %s is missing from the bundle, created CRD with synthesized schema", crd.Name)
+	cfg.report.object(path, crdGVR, u, OutcomeCreated, errSynthesizedSchema)
+
+	if err := waitForCRDsEstablished(ctx, cfg, []string{crd.Name}); err != nil {
+		return schema.GroupVersionResource{}, false, err
+	}
+
+	var (
+		gvr           schema.GroupVersionResource
+		includeStatus bool
+		detectErr     error
+	)
+	// Discovery may lag behind the established condition.
+	err = wait.PollUntilContextTimeout(ctx, 200*time.Millisecond, cfg.crdWaitTimeout, true,
+		func(context.Context) (bool, error) {
+			gvr, includeStatus, detectErr = cfg.gvrResolver.Detect(&list.Items[0])
+			return detectErr == nil, nil
+		},
+	)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, fmt.Errorf(
+			"synthesized CRD %q is not served: %w", crd.Name, errors.Join(err, detectErr))
+	}
+	return gvr, includeStatus, nil
+}
@@ -0,0 +1,186 @@
+package importer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/spf13/afero"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+)
+
+func TestSynthesizeCRD(t *testing.T) {
+	t.Parallel()
+
+	object := func(apiVersion, kind, namespace string) unstructured.Unstructured {
+		u := unstructured.Unstructured{}
+		u.SetAPIVersion(apiVersion)
+		u.SetKind(kind)
+		u.SetName("obj")
+		u.SetNamespace(namespace)
+		return u
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		object    unstructured.Unstructured
+		wantName  string
+		wantScope apiextensionsv1.ResourceScope
+		wantErr   bool
+	}{
+		{
+			name:      "namespaced resource",
+			path:      "cluster-resources/custom-resources/widgets.example.com/default.json",
+			object:    object("example.com/v1alpha1", "Widget", "default"),
+			wantName:  "widgets.example.com",
+			wantScope: apiextensionsv1.NamespaceScoped,
+		},
+		{
+			name:      "cluster-scoped resource",
+			path:      "cluster-resources/custom-resources/gadgetries.example.com.yaml",
+			object:    object("example.com/v1", "Gadget", ""),
+			wantName:  "gadgetries.example.com",
+			wantScope: apiextensionsv1.ClusterScoped,
+		},
+		{
+			name:      "plural guessed from kind",
+			path:      "cluster-resources/policies.json",
+			object:    object("policy.example.com/v1", "Policy", "default"),
+			wantName:  "policies.policy.example.com",
+			wantScope: apiextensionsv1.NamespaceScoped,
+		},
+		{
+			name:    "built-in group",
+			path:    "cluster-resources/custom-resources/deployments.apps/default.json",
+			object:  object("apps/v1", "Deployment", "default"),
+			wantErr: true,
+		},
+		{
+			name:    "unknown kind",
+			path:    "cluster-resources/custom-resources/widgets.example.com/default.json",
+			object:  object("example.com/v1", "", "default"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			list := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{tt.object}}
+			crd, err := synthesizeCRD(tt.path, list)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got CRD %q", crd.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to synthesize CRD: %v", err)
+			}
+
+			if crd.Name != tt.wantName || crd.Spec.Scope != tt.wantScope {
+				t.Fatalf("unexpected CRD %q with scope %s", crd.Name, crd.Spec.Scope)
+			}
+			if crd.Annotations[SynthesizedSchemaAnnotation] != "true" {
+				t.Fatalf("expected synthesized schema annotation, got %v", crd.Annotations)
+			}
+			if crd.Spec.Names.Kind != tt.object.GetKind() || crd.Spec.Group != tt.object.GroupVersionKind().Group {
+				t.Fatalf("unexpected CRD names %+v", crd.Spec.Names)
+			}
+
+			if len(crd.Spec.Versions) != 1 {
+				t.Fatalf("expected single version, got %d", len(crd.Spec.Versions))
+			}
+			version := crd.Spec.Versions[0]
+			if version.Name != tt.object.GroupVersionKind().Version || !version.Served || !version.Storage {
+				t.Fatalf("unexpected CRD version %+v", version)
+			}
+			schema := version.Schema.OpenAPIV3Schema
+			if schema.XPreserveUnknownFields == nil || !*schema.XPreserveUnknownFields {
+				t.Fatalf("expected schema preserving unknown fields")
+			}
+		})
+	}
+}
+
+func TestImportClusterResourcesAnnotatesAllObjectsOfSynthesizedKinds(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"cluster-resources/custom-resources/widgets.example.com/app.json": `{"items": [
+			{"apiVersion": "example.com/v1", "kind": "Widget", "metadata": {"name": "w1", "namespace": "app"}}
+		]}`,
+		"cluster-resources/custom-resources/widgets.example.com/other.json": `{"items": [
+			{"apiVersion": "example.com/v1", "kind": "Widget", "metadata": {"name": "w2", "namespace": "other"}}
+		]}`,
+	}
+	fs := afero.NewMemMapFs()
+	for path, content := range files {
+		if err := afero.WriteFile(fs, path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	widgetsGVR := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			crdGVR:     "CustomResourceDefinitionList",
+			widgetsGVR: "WidgetList",
+		},
+	)
+	fakeDiscovery := kubernetesfake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+
+	// Serve the created CRD the way the API server would.
+	client.PrependReactor("create", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		crd := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		_ = unstructured.SetNestedSlice(crd.Object, []any{
+			map[string]any{"type": "Established", "status": "True"},
+		}, "status", "conditions")
+		fakeDiscovery.Resources = append(fakeDiscovery.Resources, &metav1.APIResourceList{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "widgets", Kind: "Widget", Namespaced: true}},
+		})
+		return false, nil, nil
+	})
+
+	cfg := &importerConfig{
+		dynamicClient:  client,
+		bundle:         bundle.FromFs(fs),
+		out:            output.NewDiscardingOutput(),
+		objectPreparer: defaultObjectPreparer(),
+		gvrResolver:    newGVRResolver(fakeDiscovery),
+		crdWaitTimeout: time.Second,
+	}
+
+	if err := importClusterResources(context.Background(), cfg); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	widgets, err := client.Resource(widgetsGVR).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list widgets: %v", err)
+	}
+	if len(widgets.Items) != 2 {
+		t.Fatalf("expected 2 imported widgets, got %d", len(widgets.Items))
+	}
+	for _, w := range widgets.Items {
+		if w.GetAnnotations()[SynthesizedSchemaAnnotation] != "true" {
+			t.Fatalf("expected synthesized schema annotation on %s/%s, got %v",
+				w.GetNamespace(), w.GetName(), w.GetAnnotations())
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package kubernetes
 
 import (
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
@@ -10,9 +11,28 @@ import (
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+// ProxyContext describes a kubeconfig context for a bundle served by the http
+// proxy server.
+type ProxyContext struct {
+	Name   string
+	Config *rest.Config
+}
+
 // WriteProxyKubeconfig creates a KUBECONFIG file for http proxy server. If path
-// for kubeconfig is not provided then default value is create in `CWD`.
-func WriteProxyKubeconfig(host, path string) (string, error) {
+// for kubeconfig is not provided then default value is create in `CWD`. The CA
+// and credentials from the provided config are embedded in the file.
+func WriteProxyKubeconfig(rc *rest.Config, path string) (string, error) {
+	return WriteProxyKubeconfigContexts([]ProxyContext{{Name: "default", Config: rc}}, path)
+}
+
+// WriteProxyKubeconfigContexts creates a KUBECONFIG file with a context for
+// each of the provided bundles. The first context is set as the current
+// context.
+func WriteProxyKubeconfigContexts(contexts []ProxyContext, path string) (string, error) {
+	if len(contexts) == 0 {
+		return "", errors.New("at least one context is required")
+	}
+
 	if path == "" {
 		kubeconfigPath, _, err := getKubeconfigPathInCWD()
 		if err != nil {
@@ -21,9 +41,7 @@ func WriteProxyKubeconfig(host, path string) (string, error) {
 		path = kubeconfigPath
 	}
 
-	if err := restConfigToKubeconfig(&rest.Config{
-		Host: host,
-	}, path); err != nil {
+	if err := clientcmd.WriteToFile(proxyKubeconfig(contexts), path); err != nil {
 		return "", err
 	}
 
@@ -35,35 +53,47 @@ func WriteProxyKubeconfig(host, path string) (string, error) {
 	return absPath, nil
 }
 
-func restConfigToKubeconfig(rc *rest.Config, path string) error {
-	clusters := map[string]*clientcmdapi.Cluster{}
-	clusters["default-cluster"] = &clientcmdapi.Cluster{
-		Server:                   rc.Host,
-		CertificateAuthorityData: rc.TLSClientConfig.CAData,
+// ProxyKubeconfig returns serialized KUBECONFIG with a context for each of the
+// provided bundles.
+func ProxyKubeconfig(contexts []ProxyContext) ([]byte, error) {
+	if len(contexts) == 0 {
+		return nil, errors.New("at least one context is required")
 	}
+	return clientcmd.Write(proxyKubeconfig(contexts))
+}
 
+func proxyKubeconfig(proxyContexts []ProxyContext) clientcmdapi.Config {
+	clusters := map[string]*clientcmdapi.Cluster{}
 	contexts := map[string]*clientcmdapi.Context{}
-	contexts["default-context"] = &clientcmdapi.Context{
-		Cluster:  "default-cluster",
-		AuthInfo: "default",
-	}
-
 	authinfos := map[string]*clientcmdapi.AuthInfo{}
-	authinfos["default"] = &clientcmdapi.AuthInfo{
-		ClientKeyData:         rc.TLSClientConfig.KeyData,
-		ClientCertificateData: rc.TLSClientConfig.CertData,
+
+	for _, pc := range proxyContexts {
+		rc := pc.Config
+		clusters[pc.Name+"-cluster"] = &clientcmdapi.Cluster{
+			Server:                   rc.Host,
+			CertificateAuthorityData: rc.TLSClientConfig.CAData,
+		}
+
+		contexts[pc.Name+"-context"] = &clientcmdapi.Context{
+			Cluster:  pc.Name + "-cluster",
+			AuthInfo: pc.Name,
+		}
+
+		authinfos[pc.Name] = &clientcmdapi.AuthInfo{
+			ClientKeyData:         rc.TLSClientConfig.KeyData,
+			ClientCertificateData: rc.TLSClientConfig.CertData,
+			Token:                 rc.BearerToken,
+		}
 	}
 
-	clientConfig := clientcmdapi.Config{
+	return clientcmdapi.Config{
 		Kind:           "Config",
 		APIVersion:     "v1",
 		Clusters:       clusters,
 		Contexts:       contexts,
-		CurrentContext: "default-context",
+		CurrentContext: proxyContexts[0].Name + "-context",
 		AuthInfos:      authinfos,
 	}
-
-	return clientcmd.WriteToFile(clientConfig, path)
 }
 
 func getKubeconfigPathInCWD() (string, func(), error) {
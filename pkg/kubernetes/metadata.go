@@ -0,0 +1,61 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/metadata"
+	"k8s.io/utils/strings/slices"
+)
+
+// ObjectMetadataFn is called for each object listed by ListAllObjectsMetadata.
+type ObjectMetadataFn func(gvr schema.GroupVersionResource, o *metav1.PartialObjectMetadata) error
+
+// ListAllObjectsMetadata lists metadata of all objects of all listable
+// resources served by API server.
+func ListAllObjectsMetadata(
+	ctx context.Context,
+	discoveryClient discovery.DiscoveryInterface,
+	metadataClient metadata.Interface,
+	fn ObjectMetadataFn,
+) error {
+	resourceLists, err := discoveryClient.ServerPreferredResources()
+	if err != nil && len(resourceLists) == 0 {
+		return fmt.Errorf("failed to discover API resources: %w", err)
+	}
+
+	var listErrors []error
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			listErrors = append(listErrors, err)
+			continue
+		}
+
+		for _, apiResource := range resourceList.APIResources {
+			if strings.Contains(apiResource.Name, "/") || !slices.Contains(apiResource.Verbs, "list") {
+				continue
+			}
+
+			gvr := gv.WithResource(apiResource.Name)
+			list, err := metadataClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				listErrors = append(listErrors, fmt.Errorf("failed to list %s: %w", gvr, err))
+				continue
+			}
+
+			for i := range list.Items {
+				if err := fn(gvr, &list.Items[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return errors.Join(listErrors...)
+}
@@ -0,0 +1,239 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// APIHTTPPrefix is the HTTP prefix of the management API.
+const APIHTTPPrefix = "/management"
+
+// KubeconfigFunc returns kubeconfig for accessing the bundle via proxy.
+type KubeconfigFunc func(id string) ([]byte, error)
+
+// StatusList is a response of the list bundles request.
+type StatusList struct {
+	Items []Status `json:"items"`
+}
+
+// RegisterRequest is a request body for registering bundle from a path local
+// to the server.
+type RegisterRequest struct {
+	// ID of the bundle, derived from the path if not provided.
+	ID   string `json:"id,omitempty"`
+	Path string `json:"path"`
+}
+
+// NewAPIHandler creates handler of the management API:
+//
+//	GET    /management/bundles                    list bundles
+//	POST   /management/bundles                    register local path (JSON) or upload archive (?id=)
+//	GET    /management/bundles/{id}               bundle status, ?watch=true streams status changes
+//	GET    /management/bundles/{id}/kubeconfig    kubeconfig for the bundle
+//	DELETE /management/bundles/{id}               delete bundle
+func NewAPIHandler(m *Manager, kubeconfig KubeconfigFunc) http.Handler {
+	api := &apiHandler{manager: m, kubeconfig: kubeconfig}
+
+	r := mux.NewRouter()
+	s := r.PathPrefix(APIHTTPPrefix).Subrouter()
+	s.HandleFunc("/bundles", api.list).Methods(http.MethodGet)
+	s.HandleFunc("/bundles", api.create).Methods(http.MethodPost)
+	s.HandleFunc("/bundles/{id}", api.get).Methods(http.MethodGet)
+	s.HandleFunc("/bundles/{id}", api.delete).Methods(http.MethodDelete)
+	s.HandleFunc("/bundles/{id}/kubeconfig", api.getKubeconfig).Methods(http.MethodGet)
+
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, apierrors.NewNotFound(bundlesResource, ""))
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeError(w, apierrors.NewMethodNotSupported(bundlesResource, req.Method))
+	})
+	return r
+}
+
+type apiHandler struct {
+	manager    *Manager
+	kubeconfig KubeconfigFunc
+}
+
+func (api *apiHandler) list(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, StatusList{Items: api.manager.List()})
+}
+
+func (api *apiHandler) create(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		api.register(w, r)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, apierrors.NewBadRequest("missing id query parameter for uploaded bundle"))
+		return
+	}
+
+	maxSize := api.manager.maxUploadSize
+	if maxSize > 0 {
+		if r.ContentLength > maxSize {
+			writeError(w, uploadTooLargeError(maxSize))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	}
+
+	status, err := api.manager.Upload(id, r.Body)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		err = uploadTooLargeError(maxBytesErr.Limit)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+func uploadTooLargeError(maxSize int64) error {
+	return apierrors.NewRequestEntityTooLargeError(
+		fmt.Sprintf("bundle archive exceeds the upload size limit of %d bytes", maxSize))
+}
+
+func (api *apiHandler) register(w http.ResponseWriter, r *http.Request) {
+	req := RegisterRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, apierrors.NewBadRequest(fmt.Sprintf("invalid request body: %s", err)))
+		return
+	}
+
+	id := req.ID
+	if id == "" {
+		used := map[string]bool{}
+		for _, status := range api.manager.List() {
+			used[status.ID] = true
+		}
+		id = UniqueID(IDFromPath(req.Path), used)
+	}
+
+	status, err := api.manager.Add(id, req.Path)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+func (api *apiHandler) get(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	status, changed, err := api.manager.Get(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	watch, _ := strconv.ParseBool(r.URL.Query().Get("watch"))
+	if !watch {
+		writeJSON(w, http.StatusOK, status)
+		return
+	}
+
+	// Status changes are streamed as JSON lines until the bundle is loaded.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	for {
+		if err := encoder.Encode(status); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if status.Phase.Finished() {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		}
+
+		status, changed, err = api.manager.Get(id)
+		if err != nil {
+			// The bundle was deleted while watching.
+			return
+		}
+	}
+}
+
+func (api *apiHandler) getKubeconfig(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, _, err := api.manager.Get(id); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	data, err := api.kubeconfig(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		slog.Error("failed to write response data", "err", err)
+	}
+}
+
+func (api *apiHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := api.manager.Delete(id); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &metav1.Status{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"},
+		Status:   metav1.StatusSuccess,
+		Code:     http.StatusOK,
+		Details:  &metav1.StatusDetails{Name: id, Kind: bundlesResource.Resource},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		slog.Error("failed to write response data", "err", err)
+	}
+}
+
+// writeError writes error as k8s API `Status` object. Errors that don't carry
+// API status are reported as internal server errors.
+func writeError(w http.ResponseWriter, err error) {
+	var apiStatus apierrors.APIStatus
+	status := apierrors.NewInternalError(err).ErrStatus
+	if errors.As(err, &apiStatus) {
+		status = apiStatus.Status()
+	}
+	status.APIVersion = "v1"
+	status.Kind = "Status"
+	writeJSON(w, int(status.Code), &status)
+}
@@ -0,0 +1,141 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestAPI(t *testing.T, loader Loader, opts ...Option) (*Manager, *httptest.Server) {
+	t.Helper()
+
+	m := New(context.Background(), loader, append([]Option{WithUploadDir(t.TempDir())}, opts...)...)
+	server := httptest.NewServer(NewAPIHandler(m, func(id string) ([]byte, error) {
+		return []byte("kubeconfig for " + id), nil
+	}))
+	t.Cleanup(server.Close)
+	return m, server
+}
+
+func doRequest(t *testing.T, method, url, contentType, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestAPI_RegisterListAndDelete(t *testing.T) {
+	m, server := newTestAPI(t, &fakeLoader{})
+	bundlesURL := server.URL + APIHTTPPrefix + "/bundles"
+
+	resp := doRequest(t, http.MethodPost, bundlesURL, "application/json", `{"path": "/data/acme.tar.gz"}`)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	status := Status{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	assert.Equal(t, "acme", status.ID)
+	waitForBundle(t, m, "acme")
+
+	resp = doRequest(t, http.MethodPost, bundlesURL, "application/json", `{"path": "/data/acme.tar.gz"}`)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	assert.Equal(t, "acme-2", status.ID)
+	waitForBundle(t, m, "acme-2")
+
+	resp = doRequest(t, http.MethodGet, bundlesURL, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	list := StatusList{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list.Items, 2)
+	assert.Equal(t, PhaseReady, list.Items[0].Phase)
+
+	resp = doRequest(t, http.MethodGet, bundlesURL+"/acme/kubeconfig", "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	data := make([]byte, 64)
+	n, _ := resp.Body.Read(data)
+	assert.Equal(t, "kubeconfig for acme", string(data[:n]))
+
+	resp = doRequest(t, http.MethodDelete, bundlesURL+"/acme", "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, bundlesURL+"/acme", "", "")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	apiStatus := metav1.Status{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiStatus))
+	assert.Equal(t, metav1.StatusReasonNotFound, apiStatus.Reason)
+}
+
+func TestAPI_UploadRequiresID(t *testing.T) {
+	_, server := newTestAPI(t, &fakeLoader{})
+
+	resp := doRequest(t, http.MethodPost, server.URL+APIHTTPPrefix+"/bundles", "application/gzip", "archive")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, server.URL+APIHTTPPrefix+"/bundles?id=acme", "application/gzip", "archive")
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
+
+func TestAPI_UploadTooLarge(t *testing.T) {
+	uploadDir := t.TempDir()
+	m, server := newTestAPI(t, &fakeLoader{}, WithUploadDir(uploadDir), WithMaxUploadSize(4))
+	uploadURL := server.URL + APIHTTPPrefix + "/bundles?id=acme"
+
+	resp := doRequest(t, http.MethodPost, uploadURL, "application/gzip", "archive")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	// Chunked request without content length is limited while reading.
+	req, err := http.NewRequest(http.MethodPost, uploadURL, io.MultiReader(strings.NewReader("archive")))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/gzip")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	assert.Empty(t, m.List())
+	entries, err := os.ReadDir(uploadDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestAPI_WatchStreamsStatusUntilLoaded(t *testing.T) {
+	loader := &fakeLoader{release: make(chan struct{})}
+	m, server := newTestAPI(t, loader)
+
+	_, err := m.Add("acme", "/data/acme.tar.gz")
+	require.NoError(t, err)
+
+	resp := doRequest(t, http.MethodGet, server.URL+APIHTTPPrefix+"/bundles/acme?watch=true", "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	scanner := bufio.NewScanner(resp.Body)
+	var phases []Phase
+	for scanner.Scan() {
+		status := Status{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &status))
+		phases = append(phases, status.Phase)
+		if status.Phase == PhaseImporting {
+			close(loader.release)
+		}
+	}
+
+	require.NotEmpty(t, phases)
+	assert.Equal(t, PhaseReady, phases[len(phases)-1])
+	assert.Contains(t, phases, PhaseImporting)
+}
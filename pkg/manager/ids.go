@@ -0,0 +1,51 @@
+package manager
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var invalidIDChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// IDFromPath derives bundle ID from the bundle file or directory name.
+func IDFromPath(path string) string {
+	name := strings.ToLower(filepath.Base(filepath.Clean(path)))
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar"} {
+		if trimmed, ok := strings.CutSuffix(name, ext); ok {
+			name = trimmed
+			break
+		}
+	}
+
+	id := strings.Trim(invalidIDChars.ReplaceAllString(name, "-"), "-")
+	if len(id) > validation.DNS1123LabelMaxLength {
+		id = strings.Trim(id[:validation.DNS1123LabelMaxLength], "-")
+	}
+	if id == "" {
+		return "bundle"
+	}
+	return id
+}
+
+// UniqueID returns the ID or the ID with a numeric suffix that is not present
+// in the used IDs.
+func UniqueID(id string, used map[string]bool) string {
+	if !used[id] {
+		return id
+	}
+	for i := 2; ; i++ {
+		suffix := fmt.Sprintf("-%d", i)
+		candidate := id
+		if len(candidate)+len(suffix) > validation.DNS1123LabelMaxLength {
+			candidate = candidate[:validation.DNS1123LabelMaxLength-len(suffix)]
+		}
+		candidate += suffix
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
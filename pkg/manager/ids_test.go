@@ -0,0 +1,33 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIDFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "support-bundle.tar.gz", want: "support-bundle"},
+		{path: "/data/Acme_Bundle-2024.tgz", want: "acme-bundle-2024"},
+		{path: "/data/extracted/", want: "extracted"},
+		{path: "___.tar.gz", want: "bundle"},
+		{path: strings.Repeat("a", 70) + ".tar.gz", want: strings.Repeat("a", 63)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, IDFromPath(tt.path))
+		})
+	}
+}
+
+func TestUniqueID(t *testing.T) {
+	used := map[string]bool{"acme": true, "acme-2": true}
+	assert.Equal(t, "globex", UniqueID("globex", used))
+	assert.Equal(t, "acme-3", UniqueID("acme", used))
+}
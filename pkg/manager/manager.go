@@ -0,0 +1,398 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/importer"
+)
+
+// Phase is a lifecycle phase of a managed bundle.
+type Phase string
+
+const (
+	// PhasePending is set when the bundle is registered and waits for loading.
+	PhasePending Phase = "Pending"
+	// PhaseStarting is set while the API server for the bundle is starting.
+	PhaseStarting Phase = "Starting"
+	// PhaseImporting is set while the bundle resources are imported.
+	PhaseImporting Phase = "Importing"
+	// PhaseReady is set when the bundle is served.
+	PhaseReady Phase = "Ready"
+	// PhaseFailed is set when the bundle couldn't be loaded.
+	PhaseFailed Phase = "Failed"
+)
+
+// Finished returns true if the phase won't change anymore.
+func (p Phase) Finished() bool {
+	return p == PhaseReady || p == PhaseFailed
+}
+
+var bundlesResource = schema.GroupResource{Resource: "bundles"}
+
+// Status describes state of a managed bundle.
+type Status struct {
+	ID                string            `json:"id"`
+	Path              string            `json:"path"`
+	Phase             Phase             `json:"phase"`
+	Message           string            `json:"message,omitempty"`
+	KubernetesVersion string            `json:"kubernetesVersion,omitempty"`
+	Progress          importer.Progress `json:"progress"`
+	CreatedAt         time.Time         `json:"createdAt"`
+}
+
+// LoadRequest describes the bundle that should be loaded.
+type LoadRequest struct {
+	// ID of the bundle, the bundle is served under BundleHTTPPrefix of the ID.
+	ID string
+	// StorageID is the storage allocation identity of the bundle API server.
+	// The storage is released when the bundle is deleted, so the ID is reused
+	// when a bundle with the same ID is registered again.
+	StorageID string
+	// Path to the bundle archive or directory.
+	Path string
+}
+
+// Reporter updates status of the bundle being loaded.
+type Reporter func(update func(*Status))
+
+// Instance is a loaded bundle served by a running API server.
+type Instance interface {
+	// Handler serves requests for the bundle.
+	Handler() http.Handler
+	// Stop stops the API server of the bundle.
+	Stop() error
+}
+
+// Loader starts API servers and imports bundle resources.
+type Loader interface {
+	Load(ctx context.Context, req LoadRequest, report Reporter) (Instance, error)
+	// Release removes data stored for the bundle. It is called when the
+	// bundle is deleted, after its instance was stopped or the load failed.
+	Release(ctx context.Context, req LoadRequest) error
+}
+
+// DefaultMaxUploadSize is the default size limit of bundle archives uploaded
+// via management API.
+const DefaultMaxUploadSize int64 = 2 << 30
+
+// Option configures the manager.
+type Option func(*Manager)
+
+// WithUploadDir configures directory where uploaded bundle archives are
+// stored.
+func WithUploadDir(dir string) Option {
+	return func(m *Manager) {
+		m.uploadDir = dir
+	}
+}
+
+// WithMaxUploadSize configures size limit in bytes of bundle archives uploaded
+// via management API. Non-positive size disables the limit.
+func WithMaxUploadSize(size int64) Option {
+	return func(m *Manager) {
+		m.maxUploadSize = size
+	}
+}
+
+// Manager manages lifecycle of bundles served by a single process.
+type Manager struct {
+	ctx           context.Context
+	loader        Loader
+	uploadDir     string
+	maxUploadSize int64
+
+	mu      sync.Mutex
+	bundles map[string]*entry
+	// deleting are IDs of bundles whose storage is being released.
+	deleting map[string]struct{}
+}
+
+type entry struct {
+	req      LoadRequest
+	status   Status
+	changed  chan struct{}
+	instance Instance
+	cancel   context.CancelFunc
+	loaded   chan struct{}
+	// ownedPath is removed when the bundle is deleted.
+	ownedPath string
+}
+
+// New creates a bundle manager. Bundles are loaded in background until the
+// context is cancelled.
+func New(ctx context.Context, loader Loader, opts ...Option) *Manager {
+	m := &Manager{
+		ctx:           ctx,
+		loader:        loader,
+		uploadDir:     filepath.Join(os.TempDir(), "troubleshoot-live", "uploads"),
+		maxUploadSize: DefaultMaxUploadSize,
+		bundles:       map[string]*entry{},
+		deleting:      map[string]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Add registers bundle from a local path and starts loading it in background.
+func (m *Manager) Add(id, path string) (Status, error) {
+	return m.add(id, path, "")
+}
+
+// Upload stores bundle archive read from r and starts loading it in
+// background. The archive is removed when the bundle is deleted.
+func (m *Manager) Upload(id string, r io.Reader) (Status, error) {
+	if err := ValidateID(id); err != nil {
+		return Status{}, err
+	}
+	if err := m.checkAvailable(id); err != nil {
+		return Status{}, err
+	}
+
+	if err := os.MkdirAll(m.uploadDir, 0o755); err != nil {
+		return Status{}, fmt.Errorf("failed to create upload dir: %w", err)
+	}
+	// The bundle archive extraction is cached by the archive name so each
+	// upload needs a unique name.
+	path := filepath.Join(m.uploadDir, fmt.Sprintf("%s-%d.tar.gz", id, time.Now().UnixNano()))
+	f, err := os.Create(path)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to create bundle archive: %w", err)
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return Status{}, fmt.Errorf("failed to store bundle archive: %w", err)
+	}
+
+	status, err := m.add(id, path, path)
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return status, err
+}
+
+func (m *Manager) add(id, path, ownedPath string) (Status, error) {
+	if err := ValidateID(id); err != nil {
+		return Status{}, err
+	}
+	if path == "" {
+		return Status{}, apierrors.NewBadRequest("missing bundle path")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkAvailableLocked(id); err != nil {
+		return Status{}, err
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	e := &entry{
+		req: LoadRequest{ID: id, StorageID: id, Path: path},
+		status: Status{
+			ID:        id,
+			Path:      path,
+			Phase:     PhasePending,
+			CreatedAt: time.Now().UTC(),
+		},
+		changed:   make(chan struct{}),
+		cancel:    cancel,
+		loaded:    make(chan struct{}),
+		ownedPath: ownedPath,
+	}
+	m.bundles[id] = e
+
+	go m.load(ctx, e, e.req)
+	return e.status, nil
+}
+
+func (m *Manager) load(ctx context.Context, e *entry, req LoadRequest) {
+	defer close(e.loaded)
+
+	report := func(update func(*Status)) {
+		m.update(e, update)
+	}
+
+	instance, err := m.loader.Load(ctx, req, report)
+	if err != nil {
+		report(func(s *Status) {
+			s.Phase = PhaseFailed
+			s.Message = err.Error()
+		})
+		return
+	}
+
+	m.mu.Lock()
+	e.instance = instance
+	m.mu.Unlock()
+	report(func(s *Status) {
+		s.Phase = PhaseReady
+	})
+}
+
+func (m *Manager) update(e *entry, update func(*Status)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	update(&e.status)
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+func (m *Manager) checkAvailable(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checkAvailableLocked(id)
+}
+
+func (m *Manager) checkAvailableLocked(id string) error {
+	if _, ok := m.bundles[id]; ok {
+		return apierrors.NewAlreadyExists(bundlesResource, id)
+	}
+	if _, ok := m.deleting[id]; ok {
+		return apierrors.NewConflict(bundlesResource, id, errors.New("bundle is being deleted"))
+	}
+	return nil
+}
+
+// List returns status of all bundles sorted by ID.
+func (m *Manager) List() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]Status, 0, len(m.bundles))
+	for _, e := range m.bundles {
+		statuses = append(statuses, e.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ID < statuses[j].ID
+	})
+	return statuses
+}
+
+// Get returns status of the bundle and a channel that is closed when the
+// status changes.
+func (m *Manager) Get(id string) (Status, <-chan struct{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.bundles[id]
+	if !ok {
+		return Status{}, nil, apierrors.NewNotFound(bundlesResource, id)
+	}
+	return e.status, e.changed, nil
+}
+
+// Wait blocks until the bundle is loaded or the context is cancelled and
+// returns the final status.
+func (m *Manager) Wait(ctx context.Context, id string) (Status, error) {
+	for {
+		status, changed, err := m.Get(id)
+		if err != nil || status.Phase.Finished() {
+			return status, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Handler returns handler serving the bundle. Returns an error if the bundle
+// doesn't exist or it is not ready yet.
+func (m *Manager) Handler(id string) (http.Handler, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.bundles[id]
+	if !ok {
+		return nil, apierrors.NewNotFound(bundlesResource, id)
+	}
+	if e.instance == nil || e.status.Phase != PhaseReady {
+		return nil, apierrors.NewServiceUnavailable(
+			fmt.Sprintf("bundle %q is not ready, current phase: %s", id, e.status.Phase),
+		)
+	}
+	return e.instance.Handler(), nil
+}
+
+// Delete stops the bundle API server, releases its storage and removes the
+// bundle. Loading of the bundle is cancelled if it is still in progress. The
+// bundle ID can't be registered again until the delete finishes.
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	e, ok := m.bundles[id]
+	if ok {
+		delete(m.bundles, id)
+		m.deleting[id] = struct{}{}
+	}
+	m.mu.Unlock()
+	if !ok {
+		return apierrors.NewNotFound(bundlesResource, id)
+	}
+	defer func() {
+		m.mu.Lock()
+		delete(m.deleting, id)
+		m.mu.Unlock()
+	}()
+
+	e.cancel()
+	<-e.loaded
+
+	var errs []error
+	if e.instance != nil {
+		if err := e.instance.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop bundle %q: %w", id, err))
+		}
+	}
+	// The manager context may be already cancelled when all bundles are
+	// deleted on shutdown.
+	if err := m.loader.Release(context.WithoutCancel(m.ctx), e.req); err != nil {
+		errs = append(errs, fmt.Errorf("failed to release bundle %q storage: %w", id, err))
+	}
+	if e.ownedPath != "" {
+		if err := os.Remove(e.ownedPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove bundle %q archive: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close deletes all bundles.
+func (m *Manager) Close() error {
+	var errs []error
+	for _, status := range m.List() {
+		if err := m.Delete(status.ID); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateID returns an error if the ID can't be used as a bundle ID.
+func ValidateID(id string) error {
+	if errs := validation.IsDNS1123Label(id); len(errs) > 0 {
+		return apierrors.NewBadRequest(fmt.Sprintf("invalid bundle id %q: %s", id, errs[0]))
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/importer"
+)
+
+type fakeInstance struct {
+	stopped bool
+}
+
+func (i *fakeInstance) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (i *fakeInstance) Stop() error {
+	i.stopped = true
+	return nil
+}
+
+type fakeLoader struct {
+	mu        sync.Mutex
+	requests  []LoadRequest
+	instances map[string]*fakeInstance
+	// stored are storage IDs with data written by loaded bundles.
+	stored   map[string]bool
+	released []string
+	// release blocks loading until closed if set.
+	release chan struct{}
+	err     error
+}
+
+func (l *fakeLoader) Load(ctx context.Context, req LoadRequest, report Reporter) (Instance, error) {
+	l.mu.Lock()
+	l.requests = append(l.requests, req)
+	dirty := l.stored[req.StorageID]
+	if l.stored == nil {
+		l.stored = map[string]bool{}
+	}
+	l.stored[req.StorageID] = true
+	l.mu.Unlock()
+	if dirty {
+		return nil, fmt.Errorf("storage %q contains data of another bundle", req.StorageID)
+	}
+
+	report(func(s *Status) {
+		s.Phase = PhaseImporting
+		s.KubernetesVersion = "v1.29.0"
+		s.Progress = importer.Progress{Step: "Namespaces", Imported: 1}
+	})
+
+	if l.release != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-l.release:
+		}
+	}
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	instance := &fakeInstance{}
+	l.mu.Lock()
+	if l.instances == nil {
+		l.instances = map[string]*fakeInstance{}
+	}
+	l.instances[req.ID] = instance
+	l.mu.Unlock()
+	return instance, nil
+}
+
+func (l *fakeLoader) Release(_ context.Context, req LoadRequest) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.stored, req.StorageID)
+	l.released = append(l.released, req.StorageID)
+	return nil
+}
+
+func waitForBundle(t *testing.T, m *Manager, id string) Status {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := m.Wait(ctx, id)
+	require.NoError(t, err)
+	return status
+}
+
+func TestManager_AddAndDelete(t *testing.T) {
+	loader := &fakeLoader{}
+	m := New(context.Background(), loader)
+
+	status, err := m.Add("acme", "/data/acme.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, PhasePending, status.Phase)
+
+	_, err = m.Add("acme", "/data/other.tar.gz")
+	assert.True(t, apierrors.IsAlreadyExists(err))
+
+	status = waitForBundle(t, m, "acme")
+	assert.Equal(t, PhaseReady, status.Phase)
+	assert.Equal(t, "v1.29.0", status.KubernetesVersion)
+	assert.Equal(t, 1, status.Progress.Imported)
+
+	_, err = m.Handler("acme")
+	require.NoError(t, err)
+	assert.Len(t, m.List(), 1)
+
+	require.NoError(t, m.Delete("acme"))
+	assert.True(t, loader.instances["acme"].stopped)
+	_, err = m.Handler("acme")
+	assert.True(t, apierrors.IsNotFound(err))
+
+	assert.Equal(t, []string{"acme"}, loader.released)
+}
+
+func TestManager_DeleteAndAddReusesStorage(t *testing.T) {
+	loader := &fakeLoader{}
+	m := New(context.Background(), loader)
+
+	_, err := m.Add("acme", "/data/acme.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, PhaseReady, waitForBundle(t, m, "acme").Phase)
+	require.NoError(t, m.Delete("acme"))
+
+	// The storage was released so the bundle registered with the same ID
+	// is loaded to a clean storage.
+	_, err = m.Add("acme", "/data/other.tar.gz")
+	require.NoError(t, err)
+	status := waitForBundle(t, m, "acme")
+	assert.Equal(t, PhaseReady, status.Phase, status.Message)
+	require.Len(t, loader.requests, 2)
+	assert.Equal(t, "acme", loader.requests[0].StorageID)
+	assert.Equal(t, "acme", loader.requests[1].StorageID)
+}
+
+func TestManager_HandlerNotReady(t *testing.T) {
+	loader := &fakeLoader{release: make(chan struct{})}
+	m := New(context.Background(), loader)
+
+	_, err := m.Add("acme", "/data/acme.tar.gz")
+	require.NoError(t, err)
+
+	_, err = m.Handler("acme")
+	assert.True(t, apierrors.IsServiceUnavailable(err))
+
+	close(loader.release)
+	waitForBundle(t, m, "acme")
+	_, err = m.Handler("acme")
+	assert.NoError(t, err)
+}
+
+func TestManager_LoadFailure(t *testing.T) {
+	m := New(context.Background(), &fakeLoader{err: errors.New("broken bundle")})
+
+	_, err := m.Add("acme", "/data/acme.tar.gz")
+	require.NoError(t, err)
+
+	status := waitForBundle(t, m, "acme")
+	assert.Equal(t, PhaseFailed, status.Phase)
+	assert.Equal(t, "broken bundle", status.Message)
+}
+
+func TestManager_DeleteCancelsLoading(t *testing.T) {
+	loader := &fakeLoader{release: make(chan struct{})}
+	m := New(context.Background(), loader)
+
+	_, err := m.Add("acme", "/data/acme.tar.gz")
+	require.NoError(t, err)
+	require.NoError(t, m.Delete("acme"))
+	assert.Empty(t, m.List())
+	// Storage is released also when the loading didn't finish.
+	assert.Equal(t, []string{"acme"}, loader.released)
+}
+
+func TestManager_Upload(t *testing.T) {
+	dir := t.TempDir()
+	m := New(context.Background(), &fakeLoader{}, WithUploadDir(dir))
+
+	status, err := m.Upload("acme", strings.NewReader("archive"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(status.Path, dir))
+	assert.True(t, strings.HasSuffix(status.Path, ".tar.gz"))
+
+	data, err := os.ReadFile(status.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "archive", string(data))
+
+	_, err = m.Upload("Invalid_ID", strings.NewReader("archive"))
+	assert.True(t, apierrors.IsBadRequest(err))
+
+	waitForBundle(t, m, "acme")
+	require.NoError(t, m.Delete("acme"))
+	assert.NoFileExists(t, status.Path)
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Authenticator returns true if the request is authenticated.
+type Authenticator func(r *http.Request) bool
+
+// BearerTokenAuthenticator authenticates requests with static bearer token.
+func BearerTokenAuthenticator(token string) Authenticator {
+	return func(r *http.Request) bool {
+		value, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return false
+		}
+		return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(value)), []byte(token)) == 1
+	}
+}
+
+// ClientCertAuthenticator authenticates requests with client certificate that
+// was verified by the TLS server configuration.
+func ClientCertAuthenticator() Authenticator {
+	return func(r *http.Request) bool {
+		return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+	}
+}
+
+// healthCheckPaths can be requested without authentication so that health
+// probes don't need credentials.
+var healthCheckPaths = []string{"/livez", "/readyz", "/healthz"}
+
+// authenticationHandler rejects requests that are not authenticated by any of
+// the authenticators. The credentials are removed from authenticated requests
+// so that they are not forwarded to API server.
+func authenticationHandler(next http.Handler, prefix string, authenticators []Authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isHealthCheckRequest(r, prefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for _, authenticate := range authenticators {
+			if authenticate(r) {
+				r.Header.Del("Authorization")
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		writeStatus(w, &metav1.Status{
+			Code:    http.StatusUnauthorized,
+			Reason:  metav1.StatusReasonUnauthorized,
+			Message: "Unauthorized",
+		})
+	})
+}
+
+func isHealthCheckRequest(r *http.Request, prefix string) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	for _, healthCheckPath := range healthCheckPaths {
+		if path == healthCheckPath || strings.HasPrefix(path, healthCheckPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireAuthentication wraps handler that is not created by New so that it
+// requires requests to be authenticated by any of the authenticators.
+func RequireAuthentication(next http.Handler, authenticators ...Authenticator) http.Handler {
+	if len(authenticators) == 0 {
+		return next
+	}
+	return authenticationHandler(next, "", authenticators)
+}
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+)
+
+func TestAuthentication(t *testing.T) {
+	gotAuthorization := ""
+	proxyTarget := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h := newRouterWithPrefix(
+		"/proxy",
+		bundle.FromFs(afero.NewMemMapFs()),
+		kubernetesfake.NewClientset().CoreV1(),
+		proxyTarget,
+		WithAuthenticators(BearerTokenAuthenticator("secret"), ClientCertAuthenticator()),
+	)
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		tls           *tls.ConnectionState
+		want          int
+	}{
+		{name: "no-credentials", path: "/proxy/api/v1/pods", want: http.StatusUnauthorized},
+		{name: "invalid-token", path: "/proxy/api/v1/pods", authorization: "Bearer invalid", want: http.StatusUnauthorized},
+		{name: "valid-token", path: "/proxy/api/v1/pods", authorization: "Bearer secret", want: http.StatusOK},
+		{
+			name: "unverified-client-cert",
+			path: "/proxy/api/v1/pods",
+			tls:  &tls.ConnectionState{},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "verified-client-cert",
+			path: "/proxy/api/v1/pods",
+			tls:  &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}},
+			want: http.StatusOK,
+		},
+		{name: "health-check", path: "/proxy/readyz", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAuthorization = ""
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			req.TLS = tt.tls
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Empty(t, gotAuthorization)
+			if tt.want == http.StatusUnauthorized {
+				decodeStatus(t, rec)
+			}
+		})
+	}
+}
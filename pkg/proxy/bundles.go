@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// BundlesHTTPPrefix is the root of HTTP prefixes under which bundles are
+// served.
+const BundlesHTTPPrefix = "/bundles"
+
+// BundleHTTPPrefix returns HTTP prefix under which the bundle with given ID
+// is served.
+func BundleHTTPPrefix(id string) string {
+	return BundlesHTTPPrefix + "/" + id
+}
+
+// BundleHandlerLookup returns handler serving the bundle with given ID. The
+// returned error is written as k8s API `Status` object.
+type BundleHandlerLookup func(id string) (http.Handler, error)
+
+// NewBundlesHandler routes requests to the handlers of individual bundles. The
+// handlers must be created by New with BundleHTTPPrefix of the bundle ID as
+// the HTTP prefix.
+func NewBundlesHandler(lookup BundleHandlerLookup) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := requestBundleID(r.URL.Path)
+		if !ok {
+			writeStatus(w, notFoundStatus(fmt.Sprintf(
+				"the server could not find the requested resource, bundles are served under %s/{id}",
+				BundlesHTTPPrefix,
+			)))
+			return
+		}
+
+		h, err := lookup(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func requestBundleID(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, BundlesHTTPPrefix+"/")
+	if !ok {
+		return "", false
+	}
+	id, _, _ := strings.Cut(rest, "/")
+	return id, id != ""
+}
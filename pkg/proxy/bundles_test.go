@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+)
+
+func TestBundlesHandler(t *testing.T) {
+	newBundleHandler := func(id string, gotPath *string) http.Handler {
+		return newRouterWithPrefix(
+			BundleHTTPPrefix(id),
+			bundle.FromFs(afero.NewMemMapFs()),
+			kubernetesfake.NewClientset().CoreV1(),
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				*gotPath = id + ":" + r.URL.Path
+				w.WriteHeader(http.StatusOK)
+			}),
+		)
+	}
+
+	gotPath := ""
+	handlers := map[string]http.Handler{
+		"acme":    newBundleHandler("acme", &gotPath),
+		"acme-eu": newBundleHandler("acme-eu", &gotPath),
+	}
+	h := NewBundlesHandler(func(id string) (http.Handler, error) {
+		h, ok := handlers[id]
+		if !ok {
+			return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "bundles"}, id)
+		}
+		return h, nil
+	})
+
+	tests := []struct {
+		path     string
+		wantCode int
+		wantPath string
+	}{
+		{path: "/bundles/acme/api/v1/pods", wantCode: http.StatusOK, wantPath: "acme:/api/v1/pods"},
+		{path: "/bundles/acme-eu/api/v1/pods", wantCode: http.StatusOK, wantPath: "acme-eu:/api/v1/pods"},
+		{path: "/bundles/other/api/v1/pods", wantCode: http.StatusNotFound},
+		{path: "/api/v1/pods", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			gotPath = ""
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			require.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantPath, gotPath)
+			if tt.wantCode != http.StatusOK {
+				decodeStatus(t, rec)
+			}
+		})
+	}
+}
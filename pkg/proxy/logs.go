@@ -5,68 +5,148 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
 )
 
+// timestampPrefixRegexp matches RFC3339 timestamp that kubelet prepends to each
+// log line when logs are requested with `timestamps=true`.
+var timestampPrefixRegexp = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})) `)
+
+// defaultContainerAnnotation is used by kubectl and kubelet to select container
+// when none is specified in the request.
+const defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+// LogsHandlerOption configures logs handler.
+type LogsHandlerOption func(*logsHandlerConfig)
+
+type logsHandlerConfig struct {
+	replaySpeed     float64
+	followChunkSize int
+}
+
+// WithLogsReplaySpeed enables replay of logs requested with `follow=true`. The
+// lines are streamed with delays computed from the timestamps in the logs,
+// divided by the given speed, e.g. speed 10 replays logs 10x faster. Value 0
+// disables replay and all logs are streamed at once.
+func WithLogsReplaySpeed(speed float64) LogsHandlerOption {
+	return func(c *logsHandlerConfig) {
+		c.replaySpeed = speed
+	}
+}
+
 // LogsHandler serves logs for k8s `logs` subresource from the provided bundle.
-func LogsHandler(b bundle.Bundle, l *slog.Logger) http.HandlerFunc {
+// The pods client is used for resolving default container of a pod when the
+// container is not specified in the request.
+func LogsHandler(
+	b bundle.Bundle,
+	pods corev1client.PodsGetter,
+	l *slog.Logger,
+	opts ...LogsHandlerOption,
+) http.HandlerFunc {
+	cfg := &logsHandlerConfig{
+		followChunkSize: defaultFollowChunkSize,
+	}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	collectionTime := sync.OnceValue(func() time.Time {
+		t, err := bundle.DetectCollectionTime(b)
+		if err != nil {
+			l.Warn("failed to detect bundle collection time", "err", err)
+		} else if t.IsZero() {
+			l.Warn("bundle collection time could not be detected, no events or object timestamps found")
+		}
+		return t
+	})
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 
-		podLogsPath := ""
-
-		// Search for pod logs path in the bundle which could be collected either by the
-		// pod logs collector or by the cluster resources collector, which collects pod logs
-		// for failing pods.
-		filename := fmt.Sprintf("%s-%s.log", vars["pod"], r.URL.Query().Get("container"))
-		candidatePaths := []string{
-			filepath.Join(b.Layout().PodLogs(), vars["namespace"], filename),
-			filepath.Join(b.Layout().ClusterResources(), "pods/logs", vars["namespace"], vars["pod"], r.URL.Query().Get("container")+".log"),
+		opts, err := parsePodLogOptions(r.URL.Query())
+		if err != nil {
+			writeStatus(w, badRequestStatus(err.Error()))
+			return
 		}
-		for _, candidatePath := range candidatePaths {
-			if exists, _ := afero.Exists(b, candidatePath); exists {
-				podLogsPath = candidatePath
-				break
+
+		if opts.Container == "" {
+			pod, err := pods.Pods(vars["namespace"]).Get(r.Context(), vars["pod"], metav1.GetOptions{})
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			opts.Container, err = defaultContainer(pod)
+			if err != nil {
+				writeError(w, err)
+				return
 			}
 		}
 
+		podLogsPath := findLogsPath(b, vars["namespace"], vars["pod"], opts.Container, opts.Previous)
+		if podLogsPath == "" && opts.Previous {
+			writeStatus(w, notFoundStatus(fmt.Sprintf(
+				"previous terminated container %q in pod %q not found in the bundle", opts.Container, vars["pod"])))
+			return
+		}
+
 		if podLogsPath == "" {
-			http.Error(w, "pod logs not found in the bundle", http.StatusInternalServerError)
+			writeStatus(w, notFoundStatus(fmt.Sprintf(
+				"logs for container %q in pod %q not found in the bundle", opts.Container, vars["pod"])))
 			return
 		}
 
 		data, err := afero.ReadFile(b, podLogsPath)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 
 		l := l.With("url", r.URL, "logs source", podLogsPath)
 
+		lines := splitLogLines(data)
+		lines = filterLogLinesSince(lines, sinceTime(opts, collectionTime, lines))
+		lines = tailLogLines(lines, opts.TailLines)
+
 		// By default the `k9s` requests logs prefixed with timestamp and in the logs pane
 		// only displays a portion without the timestamp, by cutting prefix separated by first
 		// space byte(' '). The troubleshoot.sh requests logs without timestamps, which causes
 		// issues in the logs pane and for some pods the logs are cut from beginnging.
 		// This will backfill zeroed timestamp for each line.
-		if r.URL.Query().Get("timestamps") == "true" {
-			lines := bytes.Split(data, []byte("\n"))
-			timestampPrefixRegexp := regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d{6})?Z `)
-			if !timestampPrefixRegexp.Match(lines[0]) {
-				l.Debug("adding timestamp prefix to logs")
-				zeroTime := []byte(time.UnixMicro(0).Format(time.RFC3339Nano))
-				// Add prefix to each line.
-				for i := range lines {
-					lines[i] = bytes.Join([][]byte{zeroTime, lines[i]}, []byte{' '})
-				}
-				data = bytes.Join(lines, []byte("\n"))
+		if opts.Timestamps && len(lines) > 0 && !timestampPrefixRegexp.Match(lines[0]) {
+			l.Debug("adding timestamp prefix to logs")
+			zeroTime := []byte(time.UnixMicro(0).Format(time.RFC3339Nano))
+			// Add prefix to each line.
+			for i := range lines {
+				lines[i] = bytes.Join([][]byte{zeroTime, lines[i]}, []byte{' '})
+			}
+		}
+
+		if opts.Follow {
+			l.Debug("streaming logs", "replaySpeed", cfg.replaySpeed)
+			if err := followLogs(r.Context(), w, lines, opts.LimitBytes, cfg); err != nil {
+				slog.Error("failed to stream logs", "err", err)
 			}
+			return
+		}
+
+		data = joinLogLines(lines)
+		if opts.LimitBytes != nil && *opts.LimitBytes >= 0 && int64(len(data)) > *opts.LimitBytes {
+			data = data[:*opts.LimitBytes]
 		}
 
 		l.Debug("serving logs")
@@ -75,3 +155,228 @@ func LogsHandler(b bundle.Bundle, l *slog.Logger) http.HandlerFunc {
 		}
 	}
 }
+
+// defaultContainer selects container for logs request the same way as the API
+// server does, when no container is specified. The container from the default
+// container annotation is preferred, then the only container of the pod.
+func defaultContainer(pod *corev1.Pod) (string, error) {
+	if name := pod.GetAnnotations()[defaultContainerAnnotation]; name != "" {
+		for _, c := range pod.Spec.Containers {
+			if c.Name == name {
+				return name, nil
+			}
+		}
+	}
+
+	switch len(pod.Spec.Containers) {
+	case 1:
+		return pod.Spec.Containers[0].Name, nil
+	case 0:
+		return "", apierrors.NewBadRequest(fmt.Sprintf("a container name must be specified for pod %s", pod.GetName()))
+	default:
+		containerNames := make([]string, 0, len(pod.Spec.Containers))
+		for _, c := range pod.Spec.Containers {
+			containerNames = append(containerNames, c.Name)
+		}
+		message := fmt.Sprintf(
+			"a container name must be specified for pod %s, choose one of: %v", pod.GetName(), containerNames)
+
+		if len(pod.Spec.InitContainers) > 0 {
+			initContainerNames := make([]string, 0, len(pod.Spec.InitContainers))
+			for _, c := range pod.Spec.InitContainers {
+				initContainerNames = append(initContainerNames, c.Name)
+			}
+			message += fmt.Sprintf(" or one of the init containers: %v", initContainerNames)
+		}
+		return "", apierrors.NewBadRequest(message)
+	}
+}
+
+// findLogsPath searches for pod logs path in the bundle which could be collected
+// either by the pod logs collector or by the cluster resources collector, which
+// collects pod logs for failing pods. Both collectors store logs of previous
+// container instance in a file with `-previous` suffix. Empty string is returned
+// when the logs are not present in the bundle.
+func findLogsPath(b bundle.Bundle, namespace, pod, container string, previous bool) string {
+	suffix := ""
+	if previous {
+		suffix = "-previous"
+	}
+
+	candidatePaths := []string{
+		filepath.Join(b.Layout().PodLogs(), namespace, fmt.Sprintf("%s-%s%s.log", pod, container, suffix)),
+		filepath.Join(b.Layout().ClusterResources(), "pods/logs", namespace, pod, container+suffix+".log"),
+	}
+	for _, candidatePath := range candidatePaths {
+		if exists, _ := afero.Exists(b, candidatePath); exists {
+			return candidatePath
+		}
+	}
+
+	return ""
+}
+
+// parsePodLogOptions decodes `logs` subresource query parameters. The core API
+// types don't register query parameters conversion in client-go scheme so the
+// values are parsed here.
+func parsePodLogOptions(query url.Values) (*corev1.PodLogOptions, error) {
+	opts := &corev1.PodLogOptions{
+		Container: query.Get("container"),
+	}
+
+	var err error
+	if opts.Follow, err = parseBoolParam(query, "follow"); err != nil {
+		return nil, err
+	}
+	if opts.Previous, err = parseBoolParam(query, "previous"); err != nil {
+		return nil, err
+	}
+	if opts.Timestamps, err = parseBoolParam(query, "timestamps"); err != nil {
+		return nil, err
+	}
+	if opts.SinceSeconds, err = parseInt64Param(query, "sinceSeconds"); err != nil {
+		return nil, err
+	}
+	if opts.TailLines, err = parseInt64Param(query, "tailLines"); err != nil {
+		return nil, err
+	}
+	if opts.LimitBytes, err = parseInt64Param(query, "limitBytes"); err != nil {
+		return nil, err
+	}
+
+	if value := query.Get("sinceTime"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %q query parameter: %w", "sinceTime", err)
+		}
+		opts.SinceTime = &metav1.Time{Time: t}
+	}
+
+	if opts.SinceSeconds != nil && opts.SinceTime != nil {
+		return nil, fmt.Errorf("at most one of %q or %q may be specified", "sinceTime", "sinceSeconds")
+	}
+
+	return opts, nil
+}
+
+func parseBoolParam(query url.Values, name string) (bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return false, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %q query parameter: %w", name, err)
+	}
+	return parsed, nil
+}
+
+func parseInt64Param(query url.Values, name string) (*int64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %q query parameter: %w", name, err)
+	}
+	return &parsed, nil
+}
+
+// splitLogLines splits logs data to lines without the line terminators.
+func splitLogLines(data []byte) [][]byte {
+	if len(data) == 0 {
+		return nil
+	}
+	return bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+}
+
+// joinLogLines joins lines back to logs data terminating each line with a
+// newline character.
+func joinLogLines(lines [][]byte) []byte {
+	if len(lines) == 0 {
+		return nil
+	}
+	return append(bytes.Join(lines, []byte("\n")), '\n')
+}
+
+// logLineTimestamp returns timestamp parsed from the line prefix.
+func logLineTimestamp(line []byte) (time.Time, bool) {
+	match := timestampPrefixRegexp.FindSubmatch(line)
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, string(match[1]))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// sinceTime resolves the `sinceTime` and `sinceSeconds` options to an absolute
+// time. The `sinceSeconds` is relative to the time when the bundle was collected
+// and not to the current time. When the collection time is unknown the newest
+// timestamp from the logs is used instead.
+func sinceTime(opts *corev1.PodLogOptions, collectionTime func() time.Time, lines [][]byte) time.Time {
+	if opts.SinceTime != nil {
+		return opts.SinceTime.Time
+	}
+
+	if opts.SinceSeconds == nil {
+		return time.Time{}
+	}
+
+	reference := collectionTime()
+	if reference.IsZero() {
+		for i := len(lines) - 1; i >= 0; i-- {
+			if t, ok := logLineTimestamp(lines[i]); ok {
+				reference = t
+				break
+			}
+		}
+	}
+	if reference.IsZero() {
+		return time.Time{}
+	}
+
+	return reference.Add(-time.Duration(*opts.SinceSeconds) * time.Second)
+}
+
+// filterLogLinesSince removes lines older than provided time. Lines without
+// timestamp are considered to be continuation of the previous line, e.g. a
+// multiline stack trace. If the logs don't contain timestamps at all, then
+// all lines are returned.
+func filterLogLinesSince(lines [][]byte, since time.Time) [][]byte {
+	if since.IsZero() {
+		return lines
+	}
+
+	filtered := make([][]byte, 0, len(lines))
+	keep := false
+	hasTimestamps := false
+	for _, line := range lines {
+		if t, ok := logLineTimestamp(line); ok {
+			hasTimestamps = true
+			keep = !t.Before(since)
+		}
+		if keep {
+			filtered = append(filtered, line)
+		}
+	}
+
+	if !hasTimestamps {
+		return lines
+	}
+	return filtered
+}
+
+// tailLogLines returns last n lines.
+func tailLogLines(lines [][]byte, n *int64) [][]byte {
+	if n == nil || *n < 0 || int64(len(lines)) <= *n {
+		return lines
+	}
+	return lines[int64(len(lines))-*n:]
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+)
+
+// defaultFollowChunkSize is the size of data after which the streamed logs are
+// flushed to the client.
+const defaultFollowChunkSize = 32 * 1024
+
+// followLogs simulates `follow=true` logs request for a running container. The
+// logs are streamed to the client in flushed chunks and afterwards the
+// connection is kept open until the client disconnects, as if the container
+// didn't produce any new logs. If replay is configured, lines are delayed
+// according to their timestamps.
+func followLogs(
+	ctx context.Context,
+	w http.ResponseWriter,
+	lines [][]byte,
+	limitBytes *int64,
+	cfg *logsHandlerConfig,
+) error {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+
+	remaining := int64(-1)
+	if limitBytes != nil && *limitBytes >= 0 {
+		remaining = *limitBytes
+	}
+
+	chunk := &bytes.Buffer{}
+	flush := func() (bool, error) {
+		data := chunk.Bytes()
+		chunk.Reset()
+		limitReached := false
+		if remaining >= 0 && int64(len(data)) >= remaining {
+			data = data[:remaining]
+			limitReached = true
+		}
+		if remaining >= 0 {
+			remaining -= int64(len(data))
+		}
+
+		if len(data) > 0 {
+			if _, err := w.Write(data); err != nil {
+				return false, err
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return false, err
+		}
+		return limitReached, nil
+	}
+
+	var previous time.Time
+	for _, line := range lines {
+		if cfg.replaySpeed > 0 {
+			if t, ok := logLineTimestamp(line); ok {
+				if !previous.IsZero() && t.After(previous) {
+					if done, err := flush(); done || err != nil {
+						return err
+					}
+					if !sleepContext(ctx, time.Duration(float64(t.Sub(previous))/cfg.replaySpeed)) {
+						return nil
+					}
+				}
+				previous = t
+			}
+		}
+
+		chunk.Write(line)
+		chunk.WriteByte('\n')
+		if chunk.Len() >= cfg.followChunkSize {
+			if done, err := flush(); done || err != nil {
+				return err
+			}
+		}
+	}
+
+	if done, err := flush(); done || err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// sleepContext waits for the given duration and returns false if the context
+// was canceled before the duration elapsed.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
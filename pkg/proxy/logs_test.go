@@ -0,0 +1,398 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+)
+
+const testLogs = `2023-01-01T10:00:00.000000000Z first
+2023-01-01T10:05:00.000000000Z second
+  continuation of second
+2023-01-01T10:09:00.000000000Z third
+2023-01-01T10:10:00.000000000Z fourth
+`
+
+func newTestLogsBundle(t *testing.T, files map[string]string) bundle.Bundle {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o644))
+	}
+	return bundle.FromFs(fs)
+}
+
+func serveLogs(t *testing.T, b bundle.Bundle, query string, objects ...runtime.Object) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := mux.NewRouter()
+	r.Handle(
+		"/api/v1/namespaces/{namespace}/pods/{pod}/log",
+		LogsHandler(b, kubernetesfake.NewClientset(objects...).CoreV1(), slog.Default()),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/default/pods/pod-1/log?"+query, nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func readBody(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	data, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestLogsHandler_QueryParameters(t *testing.T) {
+	b := newTestLogsBundle(t, map[string]string{
+		"pod-logs/default/pod-1-app.log": testLogs,
+		"cluster-resources/events/default.json": `[
+			{"metadata": {"name": "e1"}, "lastTimestamp": "2023-01-01T10:10:00Z"}
+		]`,
+	})
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{
+			name:     "all",
+			query:    "container=app",
+			expected: testLogs,
+		},
+		{
+			name:  "tailLines",
+			query: "container=app&tailLines=2",
+			expected: "2023-01-01T10:09:00.000000000Z third\n" +
+				"2023-01-01T10:10:00.000000000Z fourth\n",
+		},
+		{
+			name:     "limitBytes",
+			query:    "container=app&limitBytes=10",
+			expected: "2023-01-01",
+		},
+		{
+			name:  "sinceSeconds relative to collection time",
+			query: "container=app&sinceSeconds=300",
+			expected: "2023-01-01T10:05:00.000000000Z second\n" +
+				"  continuation of second\n" +
+				"2023-01-01T10:09:00.000000000Z third\n" +
+				"2023-01-01T10:10:00.000000000Z fourth\n",
+		},
+		{
+			name:     "sinceTime",
+			query:    "container=app&sinceTime=2023-01-01T10:09:30Z",
+			expected: "2023-01-01T10:10:00.000000000Z fourth\n",
+		},
+		{
+			name:     "since and tail",
+			query:    "container=app&sinceSeconds=300&tailLines=1",
+			expected: "2023-01-01T10:10:00.000000000Z fourth\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveLogs(t, b, tt.query)
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.expected, readBody(t, rec))
+		})
+	}
+}
+
+func TestLogsHandler_SinceSecondsWithoutEvents(t *testing.T) {
+	b := newTestLogsBundle(t, map[string]string{
+		"pod-logs/default/pod-1-app.log": testLogs,
+	})
+
+	rec := serveLogs(t, b, "container=app&sinceSeconds=60")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t,
+		"2023-01-01T10:09:00.000000000Z third\n"+
+			"2023-01-01T10:10:00.000000000Z fourth\n",
+		readBody(t, rec),
+	)
+}
+
+func TestLogsHandler_SinceSecondsWithoutTimestamps(t *testing.T) {
+	b := newTestLogsBundle(t, map[string]string{
+		"pod-logs/default/pod-1-app.log": "first\nsecond\n",
+	})
+
+	rec := serveLogs(t, b, "container=app&sinceSeconds=60")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "first\nsecond\n", readBody(t, rec))
+}
+
+func TestLogsHandler_TimestampsBackfill(t *testing.T) {
+	b := newTestLogsBundle(t, map[string]string{
+		"pod-logs/default/pod-1-app.log": "first\nsecond\n",
+	})
+
+	rec := serveLogs(t, b, "container=app&timestamps=true")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "1970-01-01T00:00:00Z first\n1970-01-01T00:00:00Z second\n", readBody(t, rec))
+}
+
+func TestLogsHandler_InvalidQueryParameter(t *testing.T) {
+	b := newTestLogsBundle(t, map[string]string{
+		"pod-logs/default/pod-1-app.log": testLogs,
+	})
+
+	rec := serveLogs(t, b, "container=app&tailLines=abc")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestLogsHandler_Previous(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "pod logs collector", path: "pod-logs/default/pod-1-app-previous.log"},
+		{name: "cluster resources collector", path: "cluster-resources/pods/logs/default/pod-1/app-previous.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestLogsBundle(t, map[string]string{
+				"pod-logs/default/pod-1-app.log": "current\n",
+				tt.path:                          "previous\n",
+			})
+
+			rec := serveLogs(t, b, "container=app&previous=true")
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, "previous\n", readBody(t, rec))
+		})
+	}
+}
+
+func TestLogsHandler_PreviousNotFound(t *testing.T) {
+	b := newTestLogsBundle(t, map[string]string{
+		"pod-logs/default/pod-1-app.log": "current\n",
+	})
+
+	rec := serveLogs(t, b, "container=app&previous=true")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	status := decodeStatus(t, rec)
+	assert.Equal(t, metav1.StatusFailure, status.Status)
+	assert.Equal(t, metav1.StatusReasonNotFound, status.Reason)
+	assert.Contains(t, status.Message, "previous terminated container")
+}
+
+func testPod(annotations map[string]string, containers ...string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-1",
+			Namespace:   "default",
+			Annotations: annotations,
+		},
+	}
+	for _, name := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: name})
+	}
+	return pod
+}
+
+func decodeStatus(t *testing.T, rec *httptest.ResponseRecorder) *metav1.Status {
+	t.Helper()
+
+	status := &metav1.Status{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), status))
+	assert.Equal(t, "Status", status.Kind)
+	assert.Equal(t, int32(rec.Code), status.Code)
+	return status
+}
+
+func TestLogsHandler_DefaultContainer(t *testing.T) {
+	b := newTestLogsBundle(t, map[string]string{
+		"pod-logs/default/pod-1-app.log":     "app\n",
+		"pod-logs/default/pod-1-sidecar.log": "sidecar\n",
+	})
+
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		expected string
+	}{
+		{
+			name:     "single container",
+			pod:      testPod(nil, "sidecar"),
+			expected: "sidecar\n",
+		},
+		{
+			name:     "default container annotation",
+			pod:      testPod(map[string]string{defaultContainerAnnotation: "app"}, "sidecar", "app"),
+			expected: "app\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveLogs(t, b, "", tt.pod)
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.expected, readBody(t, rec))
+		})
+	}
+}
+
+func TestLogsHandler_DefaultContainerErrors(t *testing.T) {
+	b := newTestLogsBundle(t, map[string]string{
+		"pod-logs/default/pod-1-app.log": "app\n",
+	})
+
+	tests := []struct {
+		name            string
+		objects         []runtime.Object
+		expectedCode    int
+		expectedReason  metav1.StatusReason
+		expectedMessage string
+	}{
+		{
+			name:            "multiple containers",
+			objects:         []runtime.Object{testPod(nil, "app", "sidecar")},
+			expectedCode:    http.StatusBadRequest,
+			expectedReason:  metav1.StatusReasonBadRequest,
+			expectedMessage: "a container name must be specified for pod pod-1, choose one of: [app sidecar]",
+		},
+		{
+			name:           "missing pod",
+			expectedCode:   http.StatusNotFound,
+			expectedReason: metav1.StatusReasonNotFound,
+		},
+		{
+			name:            "missing logs",
+			objects:         []runtime.Object{testPod(nil, "other")},
+			expectedCode:    http.StatusNotFound,
+			expectedReason:  metav1.StatusReasonNotFound,
+			expectedMessage: `logs for container "other" in pod "pod-1" not found in the bundle`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveLogs(t, b, "", tt.objects...)
+			require.Equal(t, tt.expectedCode, rec.Code, rec.Body.String())
+
+			status := decodeStatus(t, rec)
+			assert.Equal(t, tt.expectedReason, status.Reason)
+			if tt.expectedMessage != "" {
+				assert.Equal(t, tt.expectedMessage, status.Message)
+			}
+		})
+	}
+}
+
+func TestLogsHandler_FollowKeepsConnectionOpen(t *testing.T) {
+	b := newTestLogsBundle(t, map[string]string{
+		"pod-logs/default/pod-1-app.log": testLogs,
+	})
+
+	r := mux.NewRouter()
+	r.Handle(
+		"/api/v1/namespaces/{namespace}/pods/{pod}/log",
+		LogsHandler(b, kubernetesfake.NewClientset().CoreV1(), slog.Default()),
+	)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet, server.URL+"/api/v1/namespaces/default/pods/pod-1/log?container=app&follow=true", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	data := make([]byte, len(testLogs))
+	_, err = io.ReadFull(resp.Body, data)
+	require.NoError(t, err)
+	assert.Equal(t, testLogs, string(data))
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := resp.Body.Read(make([]byte, 1))
+		readErr <- err
+	}()
+
+	select {
+	case err := <-readErr:
+		t.Fatalf("expected connection to stay open, got: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-readErr:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for connection close after client disconnect")
+	}
+}
+
+// timedResponseRecorder records time of each write to the response.
+type timedResponseRecorder struct {
+	*httptest.ResponseRecorder
+	writes []time.Time
+}
+
+func (r *timedResponseRecorder) Write(data []byte) (int, error) {
+	r.writes = append(r.writes, time.Now())
+	return r.ResponseRecorder.Write(data)
+}
+
+func TestFollowLogs_Replay(t *testing.T) {
+	lines := splitLogLines([]byte(
+		"2023-01-01T10:00:00Z first\n" +
+			"2023-01-01T10:00:05Z second\n",
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	rec := &timedResponseRecorder{ResponseRecorder: httptest.NewRecorder()}
+	err := followLogs(ctx, rec, lines, nil, &logsHandlerConfig{
+		replaySpeed:     100,
+		followChunkSize: defaultFollowChunkSize,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "2023-01-01T10:00:00Z first\n2023-01-01T10:00:05Z second\n", rec.Body.String())
+	require.Len(t, rec.writes, 2)
+	assert.GreaterOrEqual(t, rec.writes[1].Sub(rec.writes[0]), 50*time.Millisecond)
+}
+
+func TestFollowLogs_LimitBytesEndsStream(t *testing.T) {
+	lines := splitLogLines([]byte(testLogs))
+	rec := httptest.NewRecorder()
+
+	err := followLogs(context.Background(), rec, lines, ptr.To(int64(10)), &logsHandlerConfig{
+		followChunkSize: defaultFollowChunkSize,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2023-01-01", rec.Body.String())
+}
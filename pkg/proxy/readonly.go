@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DefaultReadOnlyAllowedResources lists resources that can be created in
+// read-only mode. These resources are only evaluated by API server and are not
+// persisted, e.g. `kubectl auth can-i` creates SelfSubjectAccessReview.
+var DefaultReadOnlyAllowedResources = []schema.GroupResource{
+	{Group: "authorization.k8s.io", Resource: "selfsubjectaccessreviews"},
+	{Group: "authorization.k8s.io", Resource: "selfsubjectrulesreviews"},
+	{Group: "authorization.k8s.io", Resource: "subjectaccessreviews"},
+	{Group: "authorization.k8s.io", Resource: "localsubjectaccessreviews"},
+	{Group: "authentication.k8s.io", Resource: "selfsubjectreviews"},
+	{Group: "authentication.k8s.io", Resource: "tokenreviews"},
+}
+
+// readOnlyHandler rejects requests that would modify objects in API server.
+// Requests for allowed resources and dry-run requests are forwarded.
+func readOnlyHandler(next http.Handler, allowed []schema.GroupResource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isMutatingMethod(r.Method) || isDryRun(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gr, ok := requestGroupResource(r.URL.Path)
+		if ok && slices.Contains(allowed, gr) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		resource := r.URL.Path
+		if ok {
+			resource = gr.String()
+		}
+		writeStatus(w, &metav1.Status{
+			Code:   http.StatusMethodNotAllowed,
+			Reason: metav1.StatusReasonMethodNotAllowed,
+			Message: fmt.Sprintf(
+				"proxy is running in read-only mode, %s request for %q is not allowed", r.Method, resource),
+		})
+	})
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func isDryRun(r *http.Request) bool {
+	return slices.Contains(r.URL.Query()["dryRun"], "All")
+}
+
+// requestGroupResource parses resource from API request path, e.g.
+// `/apis/apps/v1/namespaces/default/deployments/name`.
+func requestGroupResource(path string) (schema.GroupResource, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	var group string
+	switch {
+	case len(parts) >= 3 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) >= 4 && parts[0] == "apis":
+		group = parts[1]
+		parts = parts[3:]
+	default:
+		return schema.GroupResource{}, false
+	}
+
+	if len(parts) >= 3 && parts[0] == "namespaces" {
+		parts = parts[2:]
+	}
+	if len(parts) == 0 || parts[0] == "" {
+		return schema.GroupResource{}, false
+	}
+
+	return schema.GroupResource{Group: group, Resource: parts[0]}, true
+}
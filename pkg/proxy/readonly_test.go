@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+)
+
+func TestReadOnly(t *testing.T) {
+	proxyTarget := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h := newRouterWithPrefix(
+		"/proxy",
+		bundle.FromFs(afero.NewMemMapFs()),
+		kubernetesfake.NewClientset().CoreV1(),
+		proxyTarget,
+		WithReadOnly(schema.GroupResource{Group: "example.com", Resource: "widgets"}),
+	)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{method: http.MethodGet, path: "/proxy/api/v1/pods", want: http.StatusOK},
+		{method: http.MethodDelete, path: "/proxy/api/v1/namespaces/default/pods/pod-1", want: http.StatusMethodNotAllowed},
+		{method: http.MethodPost, path: "/proxy/api/v1/namespaces", want: http.StatusMethodNotAllowed},
+		{method: http.MethodPatch, path: "/proxy/apis/apps/v1/namespaces/default/deployments/d", want: http.StatusMethodNotAllowed},
+		{method: http.MethodPatch, path: "/proxy/apis/apps/v1/namespaces/default/deployments/d?dryRun=All", want: http.StatusOK},
+		{method: http.MethodPost, path: "/proxy/apis/authorization.k8s.io/v1/selfsubjectaccessreviews", want: http.StatusOK},
+		{method: http.MethodPost, path: "/proxy/apis/example.com/v1/namespaces/default/widgets", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.want, rec.Code)
+			if tt.want == http.StatusMethodNotAllowed {
+				status := decodeStatus(t, rec)
+				assert.Contains(t, status.Message, "read-only")
+			}
+		})
+	}
+}
+
+func TestRequestGroupResource(t *testing.T) {
+	tests := []struct {
+		path string
+		want schema.GroupResource
+		ok   bool
+	}{
+		{path: "/api/v1/pods", want: schema.GroupResource{Resource: "pods"}, ok: true},
+		{path: "/api/v1/namespaces/default", want: schema.GroupResource{Resource: "namespaces"}, ok: true},
+		{path: "/api/v1/namespaces/default/pods/pod-1/eviction", want: schema.GroupResource{Resource: "pods"}, ok: true},
+		{path: "/apis/apps/v1/namespaces/default/deployments", want: schema.GroupResource{Group: "apps", Resource: "deployments"}, ok: true},
+		{path: "/apis/apps/v1", ok: false},
+		{path: "/version", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := requestGroupResource(tt.path)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/importer"
+)
+
+// ImportReportPath is the path, relative to the proxy HTTP prefix, under which
+// the bundle import report is served.
+const ImportReportPath = "/troubleshoot-live/import-report"
+
+// WithImportReport serves the report of the bundle import on ImportReportPath.
+func WithImportReport(report *importer.Report) Option {
+	return func(o *options) {
+		o.importReport = report
+	}
+}
+
+// importReportHandler serves the import report as JSON. Bundles restored
+// from snapshot or imported in a previous run have no report.
+func importReportHandler(report *importer.Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if report == nil {
+			writeStatus(w, notFoundStatus(
+				"import report is not available, the bundle resources were not imported by this server"))
+			return
+		}
+
+		data, err := json.Marshal(report)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(data); err != nil {
+			slog.Error("failed to write response data", "err", err)
+		}
+	})
+}
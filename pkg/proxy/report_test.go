@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/importer"
+)
+
+func TestImportReport(t *testing.T) {
+	proxied := false
+	newHandler := func(opts ...Option) http.Handler {
+		return newRouterWithPrefix(
+			"/bundles/acme",
+			bundle.FromFs(afero.NewMemMapFs()),
+			kubernetesfake.NewClientset().CoreV1(),
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				proxied = true
+				w.WriteHeader(http.StatusOK)
+			}),
+			opts...,
+		)
+	}
+
+	report := &importer.Report{
+		Summary: []importer.ResourceSummary{{Resource: "configmaps.v1", Created: 1}},
+		Objects: []importer.ObjectResult{{
+			Version:    "v1",
+			Resource:   "configmaps",
+			Name:       "cm",
+			Namespace:  "default",
+			SourcePath: "configmaps/default.json",
+			Outcome:    importer.OutcomeCreated,
+		}},
+	}
+
+	rec := httptest.NewRecorder()
+	newHandler(WithImportReport(report)).ServeHTTP(rec,
+		httptest.NewRequest(http.MethodGet, "/bundles/acme"+ImportReportPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, proxied)
+
+	got := &importer.Report{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), got))
+	assert.Equal(t, report, got)
+
+	rec = httptest.NewRecorder()
+	newHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bundles/acme"+ImportReportPath, nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "import report is not available")
+	assert.False(t, proxied)
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
+)
+
+type includeObjectContextKey struct{}
+
+// requestFullTableObjects changes table requests to include full objects in
+// the rows so that time derived cells can be recomputed from the restored
+// bundle values. The requested policy is stored in the request context and
+// the rows are reduced back to the requested form when serving the response.
+func requestFullTableObjects(req *http.Request) {
+	if req.Method != http.MethodGet || !strings.Contains(req.Header.Get("Accept"), "as=Table") {
+		return
+	}
+
+	query := req.URL.Query()
+	requested := metav1.IncludeObjectPolicy(query.Get("includeObject"))
+	if requested == "" {
+		requested = metav1.IncludeMetadata
+	}
+	if requested == metav1.IncludeObject {
+		return
+	}
+
+	query.Set("includeObject", string(metav1.IncludeObject))
+	req.URL.RawQuery = query.Encode()
+	*req = *req.WithContext(context.WithValue(req.Context(), includeObjectContextKey{}, requested))
+}
+
+// requestedIncludeObjectPolicy returns include object policy requested by the
+// client before it was changed by requestFullTableObjects.
+func requestedIncludeObjectPolicy(req *http.Request) metav1.IncludeObjectPolicy {
+	if req != nil {
+		if policy, ok := req.Context().Value(includeObjectContextKey{}).(metav1.IncludeObjectPolicy); ok {
+			return policy
+		}
+	}
+	return metav1.IncludeObject
+}
+
+// rewriteRequestBody applies BeforeImport to objects written via proxy. This
+// reverts changes that were done to the objects when they were served, e.g.
+// shifted timestamps or original UIDs. Full objects, merge patches and delete
+// options are rewritten, other bodies are forwarded unchanged.
+func rewriteRequestBody(req *http.Request, rr rewriter.ResourceRewriter) {
+	if rr == nil || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return
+	}
+
+	if !isJSONObjectRequestContentType(req.Header.Get("Content-Type")) {
+		return
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		log.Println(err)
+		setRequestBody(req, data)
+		return
+	}
+
+	object := map[string]any{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		setRequestBody(req, data)
+		return
+	}
+
+	u := &unstructured.Unstructured{Object: object}
+	if err := rr.BeforeImport(u); err != nil {
+		log.Println(err)
+		setRequestBody(req, data)
+		return
+	}
+
+	rewritten, err := json.Marshal(u.Object)
+	if err != nil {
+		log.Println(err)
+		setRequestBody(req, data)
+		return
+	}
+	setRequestBody(req, rewritten)
+}
+
+// isJSONObjectRequestContentType returns true for request content types which
+// body is a JSON object.
+func isJSONObjectRequestContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch mediaType {
+	case "application/json", string(types.MergePatchType), string(types.StrategicMergePatchType):
+		return true
+	default:
+		return false
+	}
+}
+
+func setRequestBody(req *http.Request, data []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
+)
+
+func TestRequestFullTableObjects(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pods", nil)
+	req.Header.Set("Accept", "application/json;as=Table;v=v1;g=meta.k8s.io")
+
+	requestFullTableObjects(req)
+
+	assert.Equal(t, "Object", req.URL.Query().Get("includeObject"))
+	assert.Equal(t, metav1.IncludeMetadata, requestedIncludeObjectPolicy(req))
+}
+
+func TestRequestFullTableObjects_IgnoresNonTableRequests(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pods", nil)
+	req.Header.Set("Accept", "application/json")
+
+	requestFullTableObjects(req)
+
+	assert.Empty(t, req.URL.Query().Get("includeObject"))
+	assert.Equal(t, metav1.IncludeObject, requestedIncludeObjectPolicy(req))
+}
+
+func TestRewriteRequestBody(t *testing.T) {
+	body := `{
+		"apiVersion": "v1",
+		"kind": "ConfigMap",
+		"metadata": {"name": "cm-1", "creationTimestamp": "2023-01-02T00:00:00Z"}
+	}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/namespaces/default/configmaps/cm-1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rewriteRequestBody(req, rewriter.TimeShift(24*time.Hour))
+
+	data, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"apiVersion": "v1",
+		"kind": "ConfigMap",
+		"metadata": {"name": "cm-1", "creationTimestamp": "2023-01-01T00:00:00Z"}
+	}`, string(data))
+	assert.Equal(t, int64(len(data)), req.ContentLength)
+}
+
+func TestRewriteRequestBody_MergePatch(t *testing.T) {
+	body := `{"metadata": {"creationTimestamp": "2023-01-02T00:00:00Z"}}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/namespaces/default/configmaps/cm-1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	rewriteRequestBody(req, rewriter.TimeShift(24*time.Hour))
+
+	data, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"metadata": {"creationTimestamp": "2023-01-01T00:00:00Z"}}`, string(data))
+}
+
+func TestRewriteRequestBody_IgnoresJSONPatches(t *testing.T) {
+	body := `[{"op": "replace", "path": "/metadata/creationTimestamp", "value": "2023-01-02T00:00:00Z"}]`
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/namespaces/default/configmaps/cm-1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+
+	rewriteRequestBody(req, rewriter.TimeShift(24*time.Hour))
+
+	data, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(data))
+}
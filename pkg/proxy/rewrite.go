@@ -12,19 +12,22 @@ import (
 	"strconv"
 	"strings"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/clock"
 
 	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
 )
 
 func proxyModifyResponse(rr rewriter.ResourceRewriter) func(*http.Response) error {
-	r := &resourceRewriter{rewriter: rr}
+	r := &resourceRewriter{rewriter: rr, clock: clock.RealClock{}}
 	return r.rewriteResponseResourceFields
 }
 
 type resourceRewriter struct {
 	rewriter rewriter.ResourceRewriter
+	clock    clock.PassiveClock
 }
 
 func (rr *resourceRewriter) rewriteResponseResourceFields(r *http.Response) (returnErr error) {
@@ -49,6 +52,16 @@ func (rr *resourceRewriter) rewriteResponseResourceFields(r *http.Response) (ret
 		returnErr = writeResponseBody(r, data)
 	}()
 
+	if isTable(data) {
+		table, err := rewriteTable(data, rr.rewriter, rr.clock, requestedIncludeObjectPolicy(r.Request))
+		if err != nil {
+			log.Println(err)
+			return nil
+		}
+		data = table
+		return nil
+	}
+
 	list := &unstructured.UnstructuredList{}
 	// The condition for items > 0 is required in order to avoid processing non
 	// list requests.
@@ -64,16 +77,6 @@ func (rr *resourceRewriter) rewriteResponseResourceFields(r *http.Response) (ret
 		}
 	}
 
-	// TODO: how to handle the table out
-	// table := &metav1.Table{}
-	// if err := json.Unmarshal(data, &table); err == nil {
-	// 	fmt.Printf("%#v\n", table.ColumnDefinitions)
-	// 	if len(table.Rows) > 0 {
-	// 		row := table.Rows[0]
-	// 		fmt.Printf("object example: %#v\n", row.Object)
-	// 	}
-	// }
-
 	u := &unstructured.Unstructured{}
 	if err := json.Unmarshal(data, &u); err == nil {
 		if err := remapFields(u, rr.rewriter); err != nil {
@@ -130,12 +133,19 @@ func (rr *resourceRewriter) rewriteWatchResponse(r *http.Response) error {
 	r.ContentLength = -1
 	r.Header.Del("Content-Length")
 
-	go rr.streamWatchEvents(reader, source, gzipReader, pipeWriter, isGzipped(r))
+	go rr.streamWatchEvents(reader, source, gzipReader, pipeWriter, isGzipped(r), requestedIncludeObjectPolicy(r.Request))
 
 	return nil
 }
 
-func (rr *resourceRewriter) streamWatchEvents(reader io.Reader, source io.Closer, gzipReader *gzip.Reader, pipeWriter *io.PipeWriter, gzipOutput bool) {
+func (rr *resourceRewriter) streamWatchEvents(
+	reader io.Reader,
+	source io.Closer,
+	gzipReader *gzip.Reader,
+	pipeWriter *io.PipeWriter,
+	gzipOutput bool,
+	includeObject metav1.IncludeObjectPolicy,
+) {
 	defer source.Close()
 	if gzipReader != nil {
 		defer gzipReader.Close()
@@ -162,7 +172,7 @@ func (rr *resourceRewriter) streamWatchEvents(reader io.Reader, source io.Closer
 			return
 		}
 
-		if err := rr.rewriteWatchEvent(event); err != nil {
+		if err := rr.rewriteWatchEvent(event, includeObject); err != nil {
 			closePipeWriter(pipeWriter, gzipWriter, err)
 			return
 		}
@@ -195,12 +205,21 @@ func closePipeWriter(pipeWriter *io.PipeWriter, gzipWriter *gzip.Writer, err err
 	_ = pipeWriter.Close()
 }
 
-func (rr *resourceRewriter) rewriteWatchEvent(event map[string]json.RawMessage) error {
+func (rr *resourceRewriter) rewriteWatchEvent(event map[string]json.RawMessage, includeObject metav1.IncludeObjectPolicy) error {
 	objectData := bytes.TrimSpace(event["object"])
 	if len(objectData) == 0 || bytes.Equal(objectData, []byte("null")) || objectData[0] != '{' {
 		return nil
 	}
 
+	if isTable(objectData) {
+		data, err := rewriteTable(objectData, rr.rewriter, rr.clock, includeObject)
+		if err != nil {
+			return err
+		}
+		event["object"] = data
+		return nil
+	}
+
 	u := &unstructured.Unstructured{}
 	if err := json.Unmarshal(objectData, u); err != nil {
 		return err
@@ -14,6 +14,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
 )
@@ -193,3 +195,130 @@ func gzipString(t *testing.T, data string) string {
 	require.NoError(t, writer.Close())
 	return buf.String()
 }
+
+func TestRewriteResponseResourceFields_RewritesTableResponse(t *testing.T) {
+	body := `{
+		"kind": "Table",
+		"apiVersion": "meta.k8s.io/v1",
+		"columnDefinitions": [
+			{"name": "Name", "type": "string", "format": "name"},
+			{"name": "Status", "type": "string"},
+			{"name": "Age", "type": "string"}
+		],
+		"rows": [{
+			"cells": ["pod-1", "Running", "5s"],
+			"object": {
+				"kind": "PartialObjectMetadata",
+				"apiVersion": "meta.k8s.io/v1",
+				"metadata": {
+					"name": "pod-1",
+					"creationTimestamp": "2023-01-03T00:00:00Z",
+					"annotations": {
+						"troubleshoot-live/metadata.creationTimestamp": "\"2023-01-01T00:00:00Z\""
+					}
+				}
+			}
+		}]
+	}`
+	resp := jsonResponse(body, "")
+
+	rr := &resourceRewriter{
+		rewriter: rewriter.RemoveField("metadata", "creationTimestamp"),
+		clock:    clocktesting.NewFakePassiveClock(time.Date(2023, 1, 3, 0, 0, 5, 0, time.UTC)),
+	}
+	require.NoError(t, rr.rewriteResponseResourceFields(resp))
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	table := &metav1.Table{}
+	require.NoError(t, json.Unmarshal(data, table))
+	require.Len(t, table.Rows, 1)
+	assert.Equal(t, []any{"pod-1", "Running", "2d"}, table.Rows[0].Cells)
+
+	object := map[string]any{}
+	require.NoError(t, json.Unmarshal(table.Rows[0].Object.Raw, &object))
+	metadata := object["metadata"].(map[string]any)
+	assert.Equal(t, "2023-01-01T00:00:00Z", metadata["creationTimestamp"])
+	assert.NotContains(t, metadata["annotations"], "troubleshoot-live/metadata.creationTimestamp")
+}
+
+func TestRewriteTable_TimeDerivedCells(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	body := `{
+		"kind": "Table",
+		"apiVersion": "meta.k8s.io/v1",
+		"columnDefinitions": [
+			{"name": "Last Seen", "type": "string"},
+			{"name": "First Seen", "type": "string"},
+			{"name": "Duration", "type": "string"}
+		],
+		"rows": [
+			{
+				"cells": ["1s", "1s", ""],
+				"object": {
+					"kind": "Event",
+					"apiVersion": "v1",
+					"metadata": {"name": "event-1"},
+					"firstTimestamp": "2023-01-01T10:00:00Z",
+					"lastTimestamp": "2023-01-01T11:00:00Z"
+				}
+			},
+			{
+				"cells": ["", "", "1s"],
+				"object": {
+					"kind": "Job",
+					"apiVersion": "batch/v1",
+					"metadata": {"name": "job-1"},
+					"status": {
+						"startTime": "2023-01-01T11:00:00Z",
+						"completionTime": "2023-01-01T11:05:00Z"
+					}
+				}
+			}
+		]
+	}`
+
+	data, err := rewriteTable([]byte(body), rewriter.Multi(), clocktesting.NewFakePassiveClock(now), metav1.IncludeObject)
+	require.NoError(t, err)
+
+	table := &metav1.Table{}
+	require.NoError(t, json.Unmarshal(data, table))
+	require.Len(t, table.Rows, 2)
+	assert.Equal(t, []any{"60m", "120m", ""}, table.Rows[0].Cells)
+	assert.Equal(t, []any{"", "", "5m"}, table.Rows[1].Cells)
+}
+
+func TestRewriteTable_ReducesRowObjects(t *testing.T) {
+	body := `{
+		"kind": "Table",
+		"apiVersion": "meta.k8s.io/v1",
+		"columnDefinitions": [{"name": "Name", "type": "string"}],
+		"rows": [{
+			"cells": ["pod-1"],
+			"object": {
+				"kind": "Pod",
+				"apiVersion": "v1",
+				"metadata": {"name": "pod-1"},
+				"spec": {"nodeName": "node-1"}
+			}
+		}]
+	}`
+
+	data, err := rewriteTable([]byte(body), rewriter.Multi(), clocktesting.NewFakePassiveClock(time.Now()), metav1.IncludeMetadata)
+	require.NoError(t, err)
+	table := &metav1.Table{}
+	require.NoError(t, json.Unmarshal(data, table))
+	require.Len(t, table.Rows, 1)
+	assert.JSONEq(t,
+		`{"apiVersion": "meta.k8s.io/v1", "kind": "PartialObjectMetadata", "metadata": {"name": "pod-1"}}`,
+		string(table.Rows[0].Object.Raw),
+	)
+
+	data, err = rewriteTable([]byte(body), rewriter.Multi(), clocktesting.NewFakePassiveClock(time.Now()), metav1.IncludeNone)
+	require.NoError(t, err)
+	table = &metav1.Table{}
+	require.NoError(t, json.Unmarshal(data, table))
+	require.Len(t, table.Rows, 1)
+	assert.Empty(t, table.Rows[0].Object.Raw)
+}
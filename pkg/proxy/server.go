@@ -5,12 +5,18 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/gorilla/mux"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 
 	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/importer"
 	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
 )
 
@@ -42,8 +48,61 @@ func NormalizeHTTPPrefix(prefix string) (string, error) {
 	return p, nil
 }
 
+// Option configures proxy handler.
+type Option func(*options)
+
+type options struct {
+	logsHandlerOptions []LogsHandlerOption
+	requestRewriter    rewriter.ResourceRewriter
+	readOnly           bool
+	readOnlyAllowed    []schema.GroupResource
+	authenticators     []Authenticator
+	importReport       *importer.Report
+}
+
+// WithLogsHandlerOptions configures handler serving logs from the bundle.
+func WithLogsHandlerOptions(opts ...LogsHandlerOption) Option {
+	return func(o *options) {
+		o.logsHandlerOptions = append(o.logsHandlerOptions, opts...)
+	}
+}
+
+// WithRequestRewriter configures rewriter which BeforeImport is applied to
+// objects created or updated via proxy. It should revert modifications done by
+// the serving rewriter which are not stored in the API server.
+func WithRequestRewriter(rr rewriter.ResourceRewriter) Option {
+	return func(o *options) {
+		o.requestRewriter = rr
+	}
+}
+
+// WithReadOnly rejects requests that would modify objects in API server. The
+// allowed resources can be created in addition to
+// DefaultReadOnlyAllowedResources.
+func WithReadOnly(allowed ...schema.GroupResource) Option {
+	return func(o *options) {
+		o.readOnly = true
+		o.readOnlyAllowed = append(o.readOnlyAllowed, allowed...)
+	}
+}
+
+// WithAuthenticators requires requests to be authenticated by any of the
+// provided authenticators. Health check endpoints don't require
+// authentication.
+func WithAuthenticators(authenticators ...Authenticator) Option {
+	return func(o *options) {
+		o.authenticators = append(o.authenticators, authenticators...)
+	}
+}
+
 // New create new proxy handler that can be used by HTTP library.
-func New(cfg *rest.Config, b bundle.Bundle, rr rewriter.ResourceRewriter, httpPrefix string) (http.Handler, error) {
+func New(
+	cfg *rest.Config,
+	b bundle.Bundle,
+	rr rewriter.ResourceRewriter,
+	httpPrefix string,
+	opts ...Option,
+) (http.Handler, error) {
 	proxyHandler, err := ReverseProxyForAPIServerHandler(cfg)
 	if err != nil {
 		return nil, err
@@ -54,24 +113,75 @@ func New(cfg *rest.Config, b bundle.Bundle, rr rewriter.ResourceRewriter, httpPr
 		return nil, err
 	}
 
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataClient, err := metadata.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	uids := newUIDTranslator(apiServerUIDLoader(clientset.Discovery(), metadataClient))
+	requestRewriter := rewriter.ResourceRewriter(uids)
+	if o.requestRewriter != nil {
+		requestRewriter = rewriter.Multi(uids, o.requestRewriter)
+	}
+
+	director := proxyHandler.Director
+	proxyHandler.Director = func(req *http.Request) {
+		director(req)
+		requestFullTableObjects(req)
+		translateFieldSelectorUIDs(req, uids)
+		rewriteRequestBody(req, requestRewriter)
+	}
+
 	// disable bodyclose linting as it seems like false positive
 	// https://github.com/timakin/bodyclose/issues/42
-	proxyHandler.ModifyResponse = proxyModifyResponse(rr) //nolint:bodyclose // false positive
+	proxyHandler.ModifyResponse = proxyModifyResponse(rewriter.Multi(uids, rr)) //nolint:bodyclose // false positive
 
-	return newRouterWithPrefix(prefix, b, proxyHandler), nil
+	return newRouterWithPrefix(prefix, b, clientset.CoreV1(), proxyHandler, opts...), nil
 }
 
-func newRouterWithPrefix(prefix string, b bundle.Bundle, proxyHandler http.Handler) http.Handler {
+func newRouterWithPrefix(
+	prefix string,
+	b bundle.Bundle,
+	pods corev1client.PodsGetter,
+	proxyHandler http.Handler,
+	opts ...Option,
+) http.Handler {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	logsHandler := LogsHandler(b, pods, slog.With("handler", "LogsHandler"), o.logsHandlerOptions...)
+	reportHandler := importReportHandler(o.importReport)
+	if o.readOnly {
+		allowed := append(slices.Clone(DefaultReadOnlyAllowedResources), o.readOnlyAllowed...)
+		proxyHandler = readOnlyHandler(proxyHandler, allowed)
+	}
+
 	r := mux.NewRouter()
 	if prefix == "" {
-		r.Handle("/api/v1/namespaces/{namespace}/pods/{pod}/log", LogsHandler(b, slog.With("handler", "LogsHandler")))
+		r.Handle("/api/v1/namespaces/{namespace}/pods/{pod}/log", logsHandler)
+		r.Handle(ImportReportPath, reportHandler).Methods(http.MethodGet)
 		r.PathPrefix("/").Handler(proxyHandler)
-		return r
+	} else {
+		subrouter := r.PathPrefix(prefix).Subrouter()
+		subrouter.Handle("/api/v1/namespaces/{namespace}/pods/{pod}/log", logsHandler)
+		subrouter.Handle(ImportReportPath, reportHandler).Methods(http.MethodGet)
+		subrouter.PathPrefix("/").Handler(http.StripPrefix(prefix, proxyHandler))
 	}
 
-	subrouter := r.PathPrefix(prefix).Subrouter()
-	subrouter.Handle("/api/v1/namespaces/{namespace}/pods/{pod}/log", LogsHandler(b, slog.With("handler", "LogsHandler")))
-	subrouter.PathPrefix("/").Handler(http.StripPrefix(prefix, proxyHandler))
-
+	if len(o.authenticators) > 0 {
+		return authenticationHandler(r, prefix, o.authenticators)
+	}
 	return r
 }
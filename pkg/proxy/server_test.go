@@ -2,12 +2,13 @@ package proxy
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"net/http/httptest"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
 
 	"github.com/mhrabovcin/troubleshoot-live/pkg/bundle"
 )
@@ -51,6 +52,7 @@ func TestProxyWithPrefixStripsPrefixBeforeForwarding(t *testing.T) {
 	h := newRouterWithPrefix(
 		"/proxy",
 		bundle.FromFs(afero.NewMemMapFs()),
+		kubernetesfake.NewClientset().CoreV1(),
 		proxyTarget,
 	)
 	req := httptest.NewRequest(http.MethodGet, "/proxy/api/v1/pods", nil)
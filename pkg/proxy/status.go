@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// writeStatus writes k8s API `Status` object as a response so that clients
+// like `kubectl` or `k9s` can display a meaningful error message.
+func writeStatus(w http.ResponseWriter, status *metav1.Status) {
+	status.APIVersion = "v1"
+	status.Kind = "Status"
+	if status.Status == "" {
+		status.Status = metav1.StatusFailure
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		http.Error(w, status.Message, int(status.Code))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(status.Code))
+	if _, err := w.Write(data); err != nil {
+		slog.Error("failed to write response data", "err", err)
+	}
+}
+
+// writeError writes error as k8s API `Status` object. Errors that don't carry
+// API status are reported as internal server errors.
+func writeError(w http.ResponseWriter, err error) {
+	var apiStatus apierrors.APIStatus
+	if errors.As(err, &apiStatus) {
+		status := apiStatus.Status()
+		writeStatus(w, &status)
+		return
+	}
+
+	writeStatus(w, &apierrors.NewInternalError(err).ErrStatus)
+}
+
+func badRequestStatus(message string) *metav1.Status {
+	return &apierrors.NewBadRequest(message).ErrStatus
+}
+
+func notFoundStatus(message string) *metav1.Status {
+	return &metav1.Status{
+		Code:    http.StatusNotFound,
+		Reason:  metav1.StatusReasonNotFound,
+		Message: message,
+	}
+}
@@ -0,0 +1,194 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/utils/clock"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
+)
+
+// tableCellFn computes value of a time derived table cell from the row object.
+// The second return value is false if the value can't be computed from the
+// provided object.
+type tableCellFn func(u *unstructured.Unstructured, now time.Time) (string, bool)
+
+// timeDerivedTableColumns maps table column names which are rendered by API
+// server relative to the current time to functions that compute the value
+// from the object restored from the bundle.
+var timeDerivedTableColumns = map[string]tableCellFn{
+	"Age":        ageCell,
+	"Last Seen":  eventLastSeenCell,
+	"First Seen": eventFirstSeenCell,
+	"Duration":   jobDurationCell,
+}
+
+func isTable(data []byte) bool {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(data, &typeMeta); err != nil {
+		return false
+	}
+
+	if typeMeta.Kind != "Table" {
+		return false
+	}
+
+	return typeMeta.APIVersion == metav1.SchemeGroupVersion.String() || typeMeta.APIVersion == "meta.k8s.io/v1beta1"
+}
+
+// rewriteTable applies rewriter to objects included in the table rows and
+// recomputes time derived cells from the rewritten objects. Without this the
+// `kubectl get` output would show ages relative to the import time. The row
+// objects are reduced to the form requested by the client.
+func rewriteTable(
+	data []byte,
+	rr rewriter.ResourceRewriter,
+	clk clock.PassiveClock,
+	includeObject metav1.IncludeObjectPolicy,
+) ([]byte, error) {
+	table := &metav1.Table{}
+	if err := json.Unmarshal(data, table); err != nil {
+		return nil, err
+	}
+
+	columns := map[int]tableCellFn{}
+	for i, column := range table.ColumnDefinitions {
+		if fn, ok := timeDerivedTableColumns[column.Name]; ok {
+			columns[i] = fn
+		}
+	}
+
+	now := clk.Now()
+	for i := range table.Rows {
+		row := &table.Rows[i]
+		if len(row.Object.Raw) == 0 {
+			continue
+		}
+
+		u := &unstructured.Unstructured{}
+		if err := json.Unmarshal(row.Object.Raw, u); err != nil {
+			return nil, fmt.Errorf("failed to decode table row object: %w", err)
+		}
+
+		if err := remapFields(u, rr); err != nil {
+			log.Println(err)
+			continue
+		}
+
+		for column, fn := range columns {
+			if column >= len(row.Cells) {
+				continue
+			}
+			if value, ok := fn(u, now); ok {
+				row.Cells[column] = value
+			}
+		}
+
+		raw, err := tableRowObject(u, includeObject)
+		if err != nil {
+			return nil, err
+		}
+		row.Object.Raw = raw
+	}
+
+	return json.Marshal(table)
+}
+
+func tableRowObject(u *unstructured.Unstructured, includeObject metav1.IncludeObjectPolicy) ([]byte, error) {
+	switch includeObject {
+	case metav1.IncludeNone:
+		return nil, nil
+	case metav1.IncludeMetadata:
+		if u.GetKind() == "PartialObjectMetadata" {
+			return json.Marshal(u)
+		}
+		return json.Marshal(map[string]any{
+			"apiVersion": metav1.SchemeGroupVersion.String(),
+			"kind":       "PartialObjectMetadata",
+			"metadata":   u.Object["metadata"],
+		})
+	default:
+		return json.Marshal(u)
+	}
+}
+
+func ageCell(u *unstructured.Unstructured, now time.Time) (string, bool) {
+	return timestampSince(u, now, "metadata", "creationTimestamp")
+}
+
+// eventLastSeenCell follows the API server events table printer logic.
+func eventLastSeenCell(u *unstructured.Unstructured, now time.Time) (string, bool) {
+	if !isEvent(u) {
+		return "", false
+	}
+
+	if value, ok := timestampSince(u, now, "series", "lastObservedTime"); ok {
+		return value, true
+	}
+	if value, ok := timestampSince(u, now, "lastTimestamp"); ok {
+		return value, true
+	}
+	return eventFirstSeenCell(u, now)
+}
+
+// eventFirstSeenCell follows the API server events table printer logic.
+func eventFirstSeenCell(u *unstructured.Unstructured, now time.Time) (string, bool) {
+	if !isEvent(u) {
+		return "", false
+	}
+
+	if value, ok := timestampSince(u, now, "firstTimestamp"); ok {
+		return value, true
+	}
+	return timestampSince(u, now, "eventTime")
+}
+
+// jobDurationCell follows the API server jobs table printer logic.
+func jobDurationCell(u *unstructured.Unstructured, now time.Time) (string, bool) {
+	if u.GetKind() != "Job" {
+		return "", false
+	}
+
+	start, ok := nestedTime(u, "status", "startTime")
+	if !ok {
+		return "", false
+	}
+
+	end, ok := nestedTime(u, "status", "completionTime")
+	if !ok {
+		end = now
+	}
+
+	return duration.HumanDuration(end.Sub(start)), true
+}
+
+func isEvent(u *unstructured.Unstructured) bool {
+	return u.GetKind() == "Event"
+}
+
+func timestampSince(u *unstructured.Unstructured, now time.Time, fields ...string) (string, bool) {
+	t, ok := nestedTime(u, fields...)
+	if !ok {
+		return "", false
+	}
+	return duration.HumanDuration(now.Sub(t)), true
+}
+
+func nestedTime(u *unstructured.Unstructured, fields ...string) (time.Time, bool) {
+	value, ok, err := unstructured.NestedString(u.Object, fields...)
+	if err != nil || !ok || value == "" {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
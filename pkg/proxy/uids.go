@@ -0,0 +1,209 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/metadata"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/kubernetes"
+	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
+)
+
+var _ rewriter.ResourceRewriter = (*uidTranslator)(nil)
+
+// uidLoaderFn reports all known original and live UID pairs.
+type uidLoaderFn func(record func(original, live types.UID)) error
+
+// uidTranslator keeps mapping between UIDs of objects from the bundle and UIDs
+// generated by API server when the objects were imported. Clients only see the
+// original UIDs, so the UIDs sent by clients are translated to live UIDs before
+// the request is forwarded to API server. The objects passing the proxy are
+// recorded in the table and the table is fully loaded from API server on the
+// first lookup of unknown UID.
+type uidTranslator struct {
+	mu         sync.RWMutex
+	toLive     map[types.UID]types.UID
+	toOriginal map[types.UID]types.UID
+
+	loadOnce sync.Once
+	load     uidLoaderFn
+}
+
+func newUIDTranslator(load uidLoaderFn) *uidTranslator {
+	return &uidTranslator{
+		toLive:     map[types.UID]types.UID{},
+		toOriginal: map[types.UID]types.UID{},
+		load:       load,
+	}
+}
+
+// apiServerUIDLoader loads UIDs of all objects that were imported from the
+// bundle.
+func apiServerUIDLoader(discoveryClient discovery.DiscoveryInterface, metadataClient metadata.Interface) uidLoaderFn {
+	return func(record func(original, live types.UID)) error {
+		return kubernetes.ListAllObjectsMetadata(context.Background(), discoveryClient, metadataClient,
+			func(_ schema.GroupVersionResource, o *metav1.PartialObjectMetadata) error {
+				if original, ok := rewriter.OriginalUID(o.GetAnnotations()); ok {
+					record(original, o.GetUID())
+				}
+				return nil
+			},
+		)
+	}
+}
+
+func (t *uidTranslator) record(original, live types.UID) {
+	if original == "" || live == "" || original == live {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.toLive[original] = live
+	t.toOriginal[live] = original
+}
+
+func (t *uidTranslator) lookup(table map[types.UID]types.UID, uid types.UID) (types.UID, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	translated, ok := table[uid]
+	return translated, ok
+}
+
+// translate returns UID from given table or the provided UID if there is no
+// translation for it.
+func (t *uidTranslator) translate(table map[types.UID]types.UID, uid types.UID) types.UID {
+	if uid == "" {
+		return uid
+	}
+
+	if translated, ok := t.lookup(table, uid); ok {
+		return translated
+	}
+
+	t.loadOnce.Do(func() {
+		if t.load == nil {
+			return
+		}
+		if err := t.load(t.record); err != nil {
+			log.Printf("failed to load object UIDs: %s", err)
+		}
+	})
+
+	if translated, ok := t.lookup(table, uid); ok {
+		return translated
+	}
+	return uid
+}
+
+func (t *uidTranslator) liveUID(original types.UID) types.UID {
+	return t.translate(t.toLive, original)
+}
+
+func (t *uidTranslator) originalUID(live types.UID) types.UID {
+	return t.translate(t.toOriginal, live)
+}
+
+// BeforeImport translates original UIDs in objects sent by clients to live
+// UIDs. Objects can be full objects, patches or `DeleteOptions` and `Eviction`
+// with UID preconditions.
+func (t *uidTranslator) BeforeImport(u *unstructured.Unstructured) error {
+	translateUIDFields(u.Object, t.liveUID)
+	return nil
+}
+
+// BeforeServing records UID of the served object and translates live UIDs of
+// owners to the original UIDs. The object UID itself is restored by
+// `rewriter.GeneratedValues`.
+func (t *uidTranslator) BeforeServing(u *unstructured.Unstructured) error {
+	if original, ok := rewriter.OriginalUID(u.GetAnnotations()); ok {
+		t.record(original, u.GetUID())
+	}
+
+	translateOwnerReferencesUIDs(u.Object, t.originalUID)
+	return nil
+}
+
+func translateUIDFields(object map[string]any, translate func(types.UID) types.UID) {
+	translateStringField(object, translate, "metadata", "uid")
+	translateStringField(object, translate, "preconditions", "uid")
+	translateStringField(object, translate, "deleteOptions", "preconditions", "uid")
+	translateOwnerReferencesUIDs(object, translate)
+}
+
+func translateOwnerReferencesUIDs(object map[string]any, translate func(types.UID) types.UID) {
+	ownerReferences, ok, err := unstructured.NestedSlice(object, "metadata", "ownerReferences")
+	if err != nil || !ok {
+		return
+	}
+
+	changed := false
+	for _, ref := range ownerReferences {
+		refMap, ok := ref.(map[string]any)
+		if !ok {
+			continue
+		}
+		if translateStringField(refMap, translate, "uid") {
+			changed = true
+		}
+	}
+	if changed {
+		_ = unstructured.SetNestedSlice(object, ownerReferences, "metadata", "ownerReferences")
+	}
+}
+
+func translateStringField(object map[string]any, translate func(types.UID) types.UID, fieldPath ...string) bool {
+	value, ok, err := unstructured.NestedString(object, fieldPath...)
+	if err != nil || !ok || value == "" {
+		return false
+	}
+
+	translated := string(translate(types.UID(value)))
+	if translated == value {
+		return false
+	}
+	return unstructured.SetNestedField(object, translated, fieldPath...) == nil
+}
+
+// translateFieldSelectorUIDs translates original UIDs in `metadata.uid` field
+// selector to live UIDs. Other UID fields like `involvedObject.uid` are stored
+// with original values from the bundle and are not translated.
+func translateFieldSelectorUIDs(req *http.Request, uids *uidTranslator) {
+	query := req.URL.Query()
+	selector := query.Get("fieldSelector")
+	if selector == "" {
+		return
+	}
+
+	parsed, err := fields.ParseSelector(selector)
+	if err != nil {
+		return
+	}
+
+	changed := false
+	translated, err := parsed.Transform(func(field, value string) (string, string, error) {
+		if field != "metadata.uid" {
+			return field, value, nil
+		}
+		live := string(uids.liveUID(types.UID(value)))
+		if live != value {
+			changed = true
+		}
+		return field, live, nil
+	})
+	if err != nil || !changed {
+		return
+	}
+
+	query.Set("fieldSelector", translated.String())
+	req.URL.RawQuery = query.Encode()
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
+)
+
+func staticUIDLoader(pairs map[types.UID]types.UID) uidLoaderFn {
+	return func(record func(original, live types.UID)) error {
+		for original, live := range pairs {
+			record(original, live)
+		}
+		return nil
+	}
+}
+
+func TestUIDTranslator_BeforeServing(t *testing.T) {
+	uids := newUIDTranslator(staticUIDLoader(map[types.UID]types.UID{"original-rs": "live-rs"}))
+	u := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]any{
+			"name": "pod-1",
+			"uid":  "live-pod",
+			"annotations": map[string]any{
+				"troubleshoot-live/metadata.uid": `"original-pod"`,
+			},
+			"ownerReferences": []any{
+				map[string]any{"kind": "ReplicaSet", "name": "rs", "uid": "live-rs"},
+			},
+		},
+	}}
+
+	require.NoError(t, rewriter.Multi(uids, rewriter.Default()).BeforeServing(u))
+
+	assert.Equal(t, types.UID("original-pod"), u.GetUID())
+	assert.Equal(t, types.UID("original-rs"), u.GetOwnerReferences()[0].UID)
+	assert.Equal(t, types.UID("live-pod"), uids.liveUID("original-pod"))
+}
+
+func TestUIDTranslator_BeforeImport(t *testing.T) {
+	uids := newUIDTranslator(staticUIDLoader(map[types.UID]types.UID{
+		"original-pod": "live-pod",
+		"original-rs":  "live-rs",
+	}))
+
+	pod := &unstructured.Unstructured{Object: map[string]any{
+		"metadata": map[string]any{
+			"uid": "original-pod",
+			"ownerReferences": []any{
+				map[string]any{"kind": "ReplicaSet", "name": "rs", "uid": "original-rs"},
+				map[string]any{"kind": "Node", "name": "node", "uid": "unknown"},
+			},
+		},
+	}}
+	require.NoError(t, uids.BeforeImport(pod))
+	assert.Equal(t, types.UID("live-pod"), pod.GetUID())
+	assert.Equal(t, types.UID("live-rs"), pod.GetOwnerReferences()[0].UID)
+	assert.Equal(t, types.UID("unknown"), pod.GetOwnerReferences()[1].UID)
+
+	deleteOptions := &unstructured.Unstructured{Object: map[string]any{
+		"kind":          "DeleteOptions",
+		"preconditions": map[string]any{"uid": "original-pod"},
+	}}
+	require.NoError(t, uids.BeforeImport(deleteOptions))
+	assert.Equal(t, "live-pod", deleteOptions.Object["preconditions"].(map[string]any)["uid"])
+
+	eviction := &unstructured.Unstructured{Object: map[string]any{
+		"kind": "Eviction",
+		"deleteOptions": map[string]any{
+			"preconditions": map[string]any{"uid": "original-pod"},
+		},
+	}}
+	require.NoError(t, uids.BeforeImport(eviction))
+	uid, _, _ := unstructured.NestedString(eviction.Object, "deleteOptions", "preconditions", "uid")
+	assert.Equal(t, "live-pod", uid)
+}
+
+func TestUIDTranslator_LoadsOnce(t *testing.T) {
+	loads := 0
+	uids := newUIDTranslator(func(record func(original, live types.UID)) error {
+		loads++
+		record("original", "live")
+		return nil
+	})
+
+	assert.Equal(t, types.UID("live"), uids.liveUID("original"))
+	assert.Equal(t, types.UID("unknown"), uids.liveUID("unknown"))
+	assert.Equal(t, types.UID("original"), uids.originalUID("live"))
+	assert.Equal(t, 1, loads)
+}
+
+func TestTranslateFieldSelectorUIDs(t *testing.T) {
+	uids := newUIDTranslator(staticUIDLoader(map[types.UID]types.UID{"original-pod": "live-pod"}))
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/v1/pods?fieldSelector=metadata.uid%3Doriginal-pod%2Cmetadata.name%3Dpod-1", nil)
+	translateFieldSelectorUIDs(req, uids)
+	assert.Equal(t, "metadata.name=pod-1,metadata.uid=live-pod", req.URL.Query().Get("fieldSelector"))
+
+	req = httptest.NewRequest(http.MethodGet,
+		"/api/v1/events?fieldSelector=involvedObject.uid%3Doriginal-pod", nil)
+	translateFieldSelectorUIDs(req, uids)
+	assert.Equal(t, "involvedObject.uid=original-pod", req.URL.Query().Get("fieldSelector"))
+}
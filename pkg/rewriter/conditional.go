@@ -1,7 +1,11 @@
 package rewriter
 
 import (
+	"path"
+	"slices"
+
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -53,3 +57,74 @@ func MatchGVK(gvk schema.GroupVersionKind) Condition {
 		return true
 	}
 }
+
+// MatchGroupKind checks if resource matches given GroupKind in any version.
+func MatchGroupKind(gk schema.GroupKind) Condition {
+	return func(u *unstructured.Unstructured) bool {
+		return u.GroupVersionKind().GroupKind() == gk
+	}
+}
+
+// MatchNamespace checks if resource is in one of the given namespaces.
+func MatchNamespace(namespaces ...string) Condition {
+	return func(u *unstructured.Unstructured) bool {
+		return slices.Contains(namespaces, u.GetNamespace())
+	}
+}
+
+// MatchLabelSelector checks if resource labels match the selector.
+func MatchLabelSelector(selector labels.Selector) Condition {
+	return func(u *unstructured.Unstructured) bool {
+		return selector.Matches(labels.Set(u.GetLabels()))
+	}
+}
+
+// MatchName checks if resource name matches given glob pattern. The pattern
+// syntax is described in `path.Match`. Invalid pattern doesn't match any
+// resource.
+func MatchName(pattern string) Condition {
+	return func(u *unstructured.Unstructured) bool {
+		matched, err := path.Match(pattern, u.GetName())
+		return err == nil && matched
+	}
+}
+
+// HasField checks if resource has a field on given path.
+func HasField(fieldPath ...string) Condition {
+	return func(u *unstructured.Unstructured) bool {
+		_, ok, err := unstructured.NestedFieldNoCopy(u.Object, fieldPath...)
+		return err == nil && ok
+	}
+}
+
+// And matches if all conditions match. Empty list of conditions matches any
+// resource.
+func And(conditions ...Condition) Condition {
+	return func(u *unstructured.Unstructured) bool {
+		for _, condition := range conditions {
+			if !condition(u) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches if any of the conditions matches.
+func Or(conditions ...Condition) Condition {
+	return func(u *unstructured.Unstructured) bool {
+		for _, condition := range conditions {
+			if condition(u) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not negates the condition.
+func Not(condition Condition) Condition {
+	return func(u *unstructured.Unstructured) bool {
+		return !condition(u)
+	}
+}
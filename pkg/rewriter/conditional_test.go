@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/mhrabovcin/troubleshoot-live/pkg/rewriter"
@@ -78,3 +79,66 @@ func TestCondition_MatchGVK(t *testing.T) {
 		})
 	}
 }
+
+func testConditionObject(apiVersion, kind, namespace, name string, labels map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	u.SetLabels(labels)
+	return u
+}
+
+func TestCondition_MatchGroupKind(t *testing.T) {
+	condition := rewriter.MatchGroupKind(schema.GroupKind{Group: "apps", Kind: "Deployment"})
+	assert.True(t, condition(testConditionObject("apps/v1", "Deployment", "", "", nil)))
+	assert.True(t, condition(testConditionObject("apps/v1beta2", "Deployment", "", "", nil)))
+	assert.False(t, condition(testConditionObject("extensions/v1beta1", "Deployment", "", "", nil)))
+	assert.False(t, condition(testConditionObject("apps/v1", "StatefulSet", "", "", nil)))
+
+	core := rewriter.MatchGroupKind(schema.GroupKind{Kind: "Pod"})
+	assert.True(t, core(testConditionObject("v1", "Pod", "", "", nil)))
+}
+
+func TestCondition_MatchNamespace(t *testing.T) {
+	condition := rewriter.MatchNamespace("kube-system", "default")
+	assert.True(t, condition(testConditionObject("v1", "Pod", "default", "", nil)))
+	assert.False(t, condition(testConditionObject("v1", "Pod", "other", "", nil)))
+}
+
+func TestCondition_MatchLabelSelector(t *testing.T) {
+	selector, err := labels.Parse("app=web,tier!=db")
+	assert.NoError(t, err)
+	condition := rewriter.MatchLabelSelector(selector)
+	assert.True(t, condition(testConditionObject("v1", "Pod", "", "", map[string]string{"app": "web"})))
+	assert.False(t, condition(testConditionObject("v1", "Pod", "", "", map[string]string{"app": "web", "tier": "db"})))
+	assert.False(t, condition(testConditionObject("v1", "Pod", "", "", nil)))
+}
+
+func TestCondition_MatchName(t *testing.T) {
+	condition := rewriter.MatchName("coredns-*")
+	assert.True(t, condition(testConditionObject("v1", "Pod", "", "coredns-abc", nil)))
+	assert.False(t, condition(testConditionObject("v1", "Pod", "", "kube-proxy", nil)))
+	assert.False(t, rewriter.MatchName("[")(testConditionObject("v1", "Pod", "", "[", nil)))
+}
+
+func TestCondition_HasField(t *testing.T) {
+	u := testConditionObject("v1", "Pod", "", "", nil)
+	assert.NoError(t, unstructured.SetNestedField(u.Object, "high", "spec", "priorityClassName"))
+	assert.True(t, rewriter.HasField("spec", "priorityClassName")(u))
+	assert.False(t, rewriter.HasField("spec", "runtimeClassName")(u))
+	assert.False(t, rewriter.HasField("spec", "priorityClassName", "nested")(u))
+}
+
+func TestCondition_Combinators(t *testing.T) {
+	u := &unstructured.Unstructured{}
+	assert.True(t, rewriter.And()(u))
+	assert.True(t, rewriter.And(matchAny, matchAny)(u))
+	assert.False(t, rewriter.And(matchAny, matchNone)(u))
+	assert.False(t, rewriter.Or()(u))
+	assert.True(t, rewriter.Or(matchNone, matchAny)(u))
+	assert.False(t, rewriter.Or(matchNone, matchNone)(u))
+	assert.True(t, rewriter.Not(matchNone)(u))
+	assert.False(t, rewriter.Not(matchAny)(u))
+}
@@ -0,0 +1,214 @@
+package rewriter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// RulesConfig is a declarative configuration of rewriter rules.
+//
+// Example:
+//
+//	rules:
+//	- match:
+//	    apiVersion: v1
+//	    kind: Pod
+//	    namespace: kube-system
+//	    labels:
+//	      app: example
+//	  actions:
+//	  - removeField: spec.priority
+//	  - setField:
+//	      path: spec.enableServiceLinks
+//	      value: false
+//	  - renameAnnotation:
+//	      from: example.com/validated
+//	      to: troubleshoot-live/example.com-validated
+type RulesConfig struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule applies actions to objects matching the rule.
+type Rule struct {
+	Match   RuleMatch    `json:"match"`
+	Actions []RuleAction `json:"actions"`
+}
+
+// RuleMatch selects objects for a rule. Empty fields match any object.
+type RuleMatch struct {
+	APIVersion string            `json:"apiVersion,omitempty"`
+	Kind       string            `json:"kind,omitempty"`
+	Namespace  string            `json:"namespace,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// RuleAction is a single rewrite action. Exactly one of the fields must be set.
+type RuleAction struct {
+	// RemoveField removes field on import and restores it on serving. The
+	// field path is separated by dots, e.g. `spec.priority`.
+	RemoveField string `json:"removeField,omitempty"`
+
+	// SetField sets field to a value on import and restores the original value
+	// on serving.
+	SetField *SetFieldAction `json:"setField,omitempty"`
+
+	// RenameAnnotation renames annotation on import and renames it back on
+	// serving.
+	RenameAnnotation *RenameAnnotationAction `json:"renameAnnotation,omitempty"`
+}
+
+// SetFieldAction configures SetField rewriter.
+type SetFieldAction struct {
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+// RenameAnnotationAction configures RenameAnnotation rewriter.
+type RenameAnnotationAction struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// LoadRules loads rewriter rules from YAML file.
+func LoadRules(path string) (ResourceRewriter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rewriter rules: %w", err)
+	}
+
+	rr, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rewriter rules from %q: %w", path, err)
+	}
+	return rr, nil
+}
+
+// ParseRules creates rewriter from YAML rules configuration.
+func ParseRules(data []byte) (ResourceRewriter, error) {
+	config := &RulesConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+	return config.Rewriter()
+}
+
+// Rewriter creates rewriter that applies configured rules in order.
+func (c *RulesConfig) Rewriter() (ResourceRewriter, error) {
+	rewriters := make([]ResourceRewriter, 0, len(c.Rules))
+	var ruleErrors []error
+	for i, rule := range c.Rules {
+		rr, err := rule.rewriter()
+		if err != nil {
+			ruleErrors = append(ruleErrors, fmt.Errorf("rule %d: %w", i, err))
+			continue
+		}
+		rewriters = append(rewriters, rr)
+	}
+
+	if len(ruleErrors) > 0 {
+		return nil, errors.Join(ruleErrors...)
+	}
+	return Multi(rewriters...), nil
+}
+
+func (r Rule) rewriter() (ResourceRewriter, error) {
+	if len(r.Actions) == 0 {
+		return nil, fmt.Errorf("no actions defined")
+	}
+
+	condition, err := r.Match.condition()
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]ResourceRewriter, 0, len(r.Actions))
+	for i, action := range r.Actions {
+		rr, err := action.rewriter()
+		if err != nil {
+			return nil, fmt.Errorf("action %d: %w", i, err)
+		}
+		actions = append(actions, rr)
+	}
+
+	return When(condition, Multi(actions...)), nil
+}
+
+func (m RuleMatch) condition() (Condition, error) {
+	var conditions []Condition
+
+	if m.APIVersion != "" {
+		if _, err := schema.ParseGroupVersion(m.APIVersion); err != nil {
+			return nil, fmt.Errorf("invalid apiVersion %q: %w", m.APIVersion, err)
+		}
+		apiVersion := m.APIVersion
+		conditions = append(conditions, func(u *unstructured.Unstructured) bool {
+			return u.GetAPIVersion() == apiVersion
+		})
+	}
+
+	if m.Kind != "" {
+		kind := m.Kind
+		conditions = append(conditions, func(u *unstructured.Unstructured) bool {
+			return u.GetKind() == kind
+		})
+	}
+
+	if m.Namespace != "" {
+		conditions = append(conditions, MatchNamespace(m.Namespace))
+	}
+
+	if len(m.Labels) > 0 {
+		conditions = append(conditions, MatchLabelSelector(labels.SelectorFromSet(m.Labels)))
+	}
+
+	return And(conditions...), nil
+}
+
+func (a RuleAction) rewriter() (ResourceRewriter, error) {
+	var rewriters []ResourceRewriter
+
+	if a.RemoveField != "" {
+		path, err := parseFieldPath(a.RemoveField)
+		if err != nil {
+			return nil, err
+		}
+		rewriters = append(rewriters, RemoveField(path...))
+	}
+
+	if a.SetField != nil {
+		path, err := parseFieldPath(a.SetField.Path)
+		if err != nil {
+			return nil, err
+		}
+		rewriters = append(rewriters, SetField(a.SetField.Value, path...))
+	}
+
+	if a.RenameAnnotation != nil {
+		if a.RenameAnnotation.From == "" || a.RenameAnnotation.To == "" {
+			return nil, fmt.Errorf("renameAnnotation requires both from and to")
+		}
+		rewriters = append(rewriters, RenameAnnotation(a.RenameAnnotation.From, a.RenameAnnotation.To))
+	}
+
+	if len(rewriters) != 1 {
+		return nil, fmt.Errorf("exactly one of removeField, setField or renameAnnotation must be set")
+	}
+	return rewriters[0], nil
+}
+
+func parseFieldPath(path string) ([]string, error) {
+	fieldPath := strings.Split(strings.TrimPrefix(path, "."), ".")
+	for _, field := range fieldPath {
+		if field == "" {
+			return nil, fmt.Errorf("invalid field path %q", path)
+		}
+	}
+	return fieldPath, nil
+}
@@ -0,0 +1,100 @@
+package rewriter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+const testRulesConfig = `
+rules:
+- match:
+    apiVersion: v1
+    kind: Pod
+    namespace: kube-system
+    labels:
+      app: example
+  actions:
+  - removeField: spec.priority
+  - setField:
+      path: spec.enableServiceLinks
+      value: false
+  - renameAnnotation:
+      from: example.com/validated
+      to: troubleshoot-live/validated
+`
+
+func TestParseRules(t *testing.T) {
+	rr, err := ParseRules([]byte(testRulesConfig))
+	require.NoError(t, err)
+
+	newPod := func(namespace string) *corev1.Pod {
+		return &corev1.Pod{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Labels:      map[string]string{"app": "example"},
+				Annotations: map[string]string{"example.com/validated": "true"},
+			},
+			Spec: corev1.PodSpec{
+				Priority:           ptr.To(int32(1000)),
+				EnableServiceLinks: ptr.To(true),
+			},
+		}
+	}
+
+	pod := testRewriterBeforeImport(t, rr, newPod("kube-system"))
+	assert.Nil(t, pod.Spec.Priority)
+	assert.Equal(t, ptr.To(false), pod.Spec.EnableServiceLinks)
+	assert.Equal(t, "true", pod.GetAnnotations()["troubleshoot-live/validated"])
+	assert.NotContains(t, pod.GetAnnotations(), "example.com/validated")
+
+	pod = testRewriterBeforeServing(t, rr, pod)
+	assert.Equal(t, newPod("kube-system"), pod)
+
+	other := testRewriterBeforeImport(t, rr, newPod("default"))
+	assert.Equal(t, newPod("default"), other)
+}
+
+func TestParseRules_Invalid(t *testing.T) {
+	testCases := map[string]string{
+		"unknown field": `
+rules:
+- match: {kind: Pod}
+  actions:
+  - dropField: spec.priority
+`,
+		"no actions": `
+rules:
+- match: {kind: Pod}
+`,
+		"multiple action types": `
+rules:
+- actions:
+  - removeField: spec.priority
+    renameAnnotation: {from: a, to: b}
+`,
+		"invalid path": `
+rules:
+- actions:
+  - removeField: spec..priority
+`,
+		"invalid apiVersion": `
+rules:
+- match: {apiVersion: a/b/c}
+  actions:
+  - removeField: spec.priority
+`,
+	}
+
+	for name, config := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseRules([]byte(config))
+			assert.Error(t, err)
+		})
+	}
+}
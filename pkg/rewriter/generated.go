@@ -1,5 +1,17 @@
 package rewriter
 
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// maxManagedFieldsSize limits size of preserved `managedFields`. Objects
+// applied with server-side apply (e.g. large CRDs) can have managed fields
+// that wouldn't fit into the annotations size limit together with the other
+// annotations of the object.
+const maxManagedFieldsSize = 64 * 1024
+
 // GeneratedValues removes generated values.
 // See: https://kubernetes.io/docs/reference/using-api/api-concepts/#generated-values
 func GeneratedValues() ResourceRewriter {
@@ -10,5 +22,22 @@ func GeneratedValues() ResourceRewriter {
 		RemoveField("metadata", "deletionGracePeriodSeconds"),
 		RemoveField("metadata", "uid"),
 		RemoveField("metadata", "resourceVersion"),
+		RemoveField("metadata", "generation"),
+		RemoveFieldUpToSize(maxManagedFieldsSize, "metadata", "managedFields"),
 	)
 }
+
+// OriginalUID returns UID of the object from the bundle that was stored by
+// GeneratedValues rewriter when the object was imported.
+func OriginalUID(annotations map[string]string) (types.UID, bool) {
+	value, ok := annotations[annotationForField("metadata", "uid")]
+	if !ok {
+		return "", false
+	}
+
+	var uid string
+	if err := json.Unmarshal([]byte(value), &uid); err != nil || uid == "" {
+		return "", false
+	}
+	return types.UID(uid), true
+}
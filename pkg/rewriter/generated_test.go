@@ -3,11 +3,14 @@ package rewriter
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/ptr"
@@ -24,6 +27,10 @@ func TestGeneratedValues(t *testing.T) {
 			DeletionGracePeriodSeconds: ptr.To(int64(30)),
 			UID:                        types.UID("1000"),
 			ResourceVersion:            "2000",
+			Generation:                 3,
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "v1"},
+			},
 		},
 	}
 	pod = testRewriterBeforeImport(t, r, pod)
@@ -33,6 +40,8 @@ func TestGeneratedValues(t *testing.T) {
 	assert.Empty(t, pod.GetDeletionGracePeriodSeconds())
 	assert.Empty(t, pod.GetUID())
 	assert.Empty(t, pod.GetResourceVersion())
+	assert.Empty(t, pod.GetGeneration())
+	assert.Empty(t, pod.GetManagedFields())
 
 	expectedFields := map[string]any{
 		"generateName":               "generated-",
@@ -41,6 +50,7 @@ func TestGeneratedValues(t *testing.T) {
 		"deletionGracePeriodSeconds": 30,
 		"uid":                        "1000",
 		"resourceVersion":            "2000",
+		"generation":                 3,
 	}
 	for k, v := range expectedFields {
 		fieldName := fmt.Sprintf("metadata.%s", k)
@@ -58,4 +68,69 @@ func TestGeneratedValues(t *testing.T) {
 	assert.EqualValues(t, 30, ptr.Deref(pod.GetDeletionGracePeriodSeconds(), 0))
 	assert.Equal(t, types.UID("1000"), pod.GetUID())
 	assert.Equal(t, "2000", pod.GetResourceVersion())
+	assert.EqualValues(t, 3, pod.GetGeneration())
+	require.Len(t, pod.GetManagedFields(), 1)
+	assert.Equal(t, "kubectl", pod.GetManagedFields()[0].Manager)
+}
+
+func TestGeneratedValues_LargeManagedFields(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: strings.Repeat("m", maxManagedFieldsSize)},
+			},
+		},
+	}
+	pod = testRewriterBeforeImport(t, GeneratedValues(), pod)
+	assert.Empty(t, pod.GetManagedFields())
+	assert.NotContains(t, pod.GetAnnotations(), annotationForOriginalValue("metadata.managedFields"))
+	assert.Contains(t, pod.GetAnnotations(), annotationForOriginalValue("metadata.managedFields-dropped"))
+
+	pod = testRewriterBeforeServing(t, GeneratedValues(), pod)
+	assert.Empty(t, pod.GetManagedFields())
+	assert.NotContains(t, pod.GetAnnotations(), annotationForOriginalValue("metadata.managedFields-dropped"))
+}
+
+func TestGeneratedValues_ManagedFieldsOverAnnotationsLimit(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"large": strings.Repeat("a", apivalidation.TotalAnnotationSizeLimitB-maxManagedFieldsSize/2),
+			},
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: strings.Repeat("m", maxManagedFieldsSize/2)},
+			},
+		},
+	}
+	pod = testRewriterBeforeImport(t, GeneratedValues(), pod)
+	assert.Empty(t, pod.GetManagedFields())
+	assert.NotContains(t, pod.GetAnnotations(), annotationForOriginalValue("metadata.managedFields"))
+	assert.Contains(t, pod.GetAnnotations(), annotationForOriginalValue("metadata.managedFields-dropped"))
+
+	size := 0
+	for k, v := range pod.GetAnnotations() {
+		size += len(k) + len(v)
+	}
+	assert.LessOrEqual(t, size, apivalidation.TotalAnnotationSizeLimitB)
+
+	pod = testRewriterBeforeServing(t, GeneratedValues(), pod)
+	assert.Empty(t, pod.GetManagedFields())
+	assert.NotContains(t, pod.GetAnnotations(), annotationForOriginalValue("metadata.managedFields-dropped"))
+	assert.Contains(t, pod.GetAnnotations(), "large")
+}
+
+func TestOriginalUID(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			UID: types.UID("1000"),
+		},
+	}
+	pod = testRewriterBeforeImport(t, GeneratedValues(), pod)
+
+	uid, ok := OriginalUID(pod.GetAnnotations())
+	assert.True(t, ok)
+	assert.Equal(t, types.UID("1000"), uid)
+
+	_, ok = OriginalUID(map[string]string{})
+	assert.False(t, ok)
 }
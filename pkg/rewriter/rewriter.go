@@ -3,8 +3,10 @@ package rewriter
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
@@ -17,6 +19,16 @@ func annotationForField(fieldPath ...string) string {
 	return annotationForOriginalValue(strings.Join(fieldPath, "."))
 }
 
+// annotationForDroppedField creates annotation key that records size of the
+// original value which was too large to be kept.
+func annotationForDroppedField(fieldPath ...string) string {
+	return annotationForField(fieldPath...) + "-dropped"
+}
+
+// annotationsSizeReserve is kept free of the API server annotations size limit
+// for annotations added after the original values are stored.
+const annotationsSizeReserve = 4 * 1024
+
 //go:generate go tool mockery --name ResourceRewriter --with-expecter
 
 // ResourceRewriter prepares object for saving on import and rewrites the object
@@ -39,8 +51,21 @@ func RemoveField(path ...string) ResourceRewriter {
 	}
 }
 
+// RemoveFieldUpToSize works like RemoveField but the original value is kept
+// only if its serialized size doesn't exceed maxSize bytes and the object
+// annotations with the value fit into the API server limit. Size of dropped
+// values is recorded in `<annotation>-dropped` annotation which is removed
+// before serving.
+func RemoveFieldUpToSize(maxSize int, path ...string) ResourceRewriter {
+	return &removeField{
+		fieldPath: path,
+		maxSize:   maxSize,
+	}
+}
+
 type removeField struct {
 	fieldPath []string
+	maxSize   int
 }
 
 func (r *removeField) annotationName() string {
@@ -64,10 +89,29 @@ func (r *removeField) BeforeImport(u *unstructured.Unstructured) error {
 		return err
 	}
 
+	if r.maxSize > 0 && !r.fits(u, serialized) {
+		return addAnnotation(u, annotationForDroppedField(r.fieldPath...), strconv.Itoa(len(serialized)))
+	}
+
 	return addAnnotation(u, r.annotationName(), string(serialized))
 }
 
+// fits returns true if the serialized value can be stored in the annotation.
+func (r *removeField) fits(u *unstructured.Unstructured, serialized []byte) bool {
+	if len(serialized) > r.maxSize {
+		return false
+	}
+
+	size := len(r.annotationName()) + len(serialized)
+	for k, v := range u.GetAnnotations() {
+		size += len(k) + len(v)
+	}
+	return size <= apivalidation.TotalAnnotationSizeLimitB-annotationsSizeReserve
+}
+
 func (r *removeField) BeforeServing(u *unstructured.Unstructured) error {
+	unstructured.RemoveNestedField(u.Object, "metadata", "annotations", annotationForDroppedField(r.fieldPath...))
+
 	value, ok, err := unstructured.NestedString(u.Object, "metadata", "annotations", r.annotationName())
 	if err != nil {
 		return err
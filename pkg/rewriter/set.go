@@ -0,0 +1,135 @@
+package rewriter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ ResourceRewriter = (*setField)(nil)
+
+// SetField sets field to given value on import and stores the original value
+// so that it can be restored on serving. Missing original field is removed on
+// serving.
+func SetField(value any, path ...string) ResourceRewriter {
+	return &setField{
+		value:     value,
+		fieldPath: path,
+	}
+}
+
+type setField struct {
+	value     any
+	fieldPath []string
+}
+
+func (r *setField) annotationName() string {
+	return annotationForField(r.fieldPath...)
+}
+
+func (r *setField) BeforeImport(u *unstructured.Unstructured) error {
+	original, ok, err := unstructured.NestedFieldNoCopy(u.Object, r.fieldPath...)
+	if err != nil {
+		return err
+	}
+
+	var originalValue any
+	if ok {
+		originalValue = original
+	}
+
+	serialized, err := json.Marshal(originalValue)
+	if err != nil {
+		return fmt.Errorf("failed to serialize original .%s: %w", strings.Join(r.fieldPath, "."), err)
+	}
+
+	value, err := jsonValue(r.value)
+	if err != nil {
+		return fmt.Errorf("invalid value for .%s: %w", strings.Join(r.fieldPath, "."), err)
+	}
+
+	if err := unstructured.SetNestedField(u.Object, value, r.fieldPath...); err != nil {
+		return fmt.Errorf("failed to set .%s: %w", strings.Join(r.fieldPath, "."), err)
+	}
+
+	return addAnnotation(u, r.annotationName(), string(serialized))
+}
+
+func (r *setField) BeforeServing(u *unstructured.Unstructured) error {
+	serialized, ok, err := unstructured.NestedString(u.Object, "metadata", "annotations", r.annotationName())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	var originalValue any
+	if err := json.Unmarshal([]byte(serialized), &originalValue); err != nil {
+		return fmt.Errorf("failed to deserialize original .%s: %w", strings.Join(r.fieldPath, "."), err)
+	}
+
+	if originalValue == nil {
+		unstructured.RemoveNestedField(u.Object, r.fieldPath...)
+	} else if err := unstructured.SetNestedField(u.Object, originalValue, r.fieldPath...); err != nil {
+		return fmt.Errorf("failed to restore original .%s: %w", strings.Join(r.fieldPath, "."), err)
+	}
+
+	unstructured.RemoveNestedField(u.Object, "metadata", "annotations", r.annotationName())
+	return nil
+}
+
+// jsonValue converts value to a copy that contains only JSON compatible types
+// which can be stored in unstructured object.
+func jsonValue(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var converted any
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+var _ ResourceRewriter = (*renameAnnotation)(nil)
+
+// RenameAnnotation renames annotation on import and renames it back on
+// serving. This can be used for annotations that are interpreted by API server
+// admission.
+func RenameAnnotation(from, to string) ResourceRewriter {
+	return &renameAnnotation{
+		from: from,
+		to:   to,
+	}
+}
+
+type renameAnnotation struct {
+	from string
+	to   string
+}
+
+func (r *renameAnnotation) BeforeImport(u *unstructured.Unstructured) error {
+	return moveAnnotation(u, r.from, r.to)
+}
+
+func (r *renameAnnotation) BeforeServing(u *unstructured.Unstructured) error {
+	return moveAnnotation(u, r.to, r.from)
+}
+
+func moveAnnotation(u *unstructured.Unstructured, from, to string) error {
+	value, ok, err := unstructured.NestedString(u.Object, "metadata", "annotations", from)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	unstructured.RemoveNestedField(u.Object, "metadata", "annotations", from)
+	return addAnnotation(u, to, value)
+}
@@ -0,0 +1,53 @@
+package rewriter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestSetField_SetAndRestore(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			EnableServiceLinks: ptr.To(true),
+		},
+	}
+	r := SetField(false, "spec", "enableServiceLinks")
+
+	pod = testRewriterBeforeImport(t, r, pod)
+	assert.Equal(t, ptr.To(false), pod.Spec.EnableServiceLinks)
+	assert.Contains(t, pod.GetAnnotations(), annotationForField("spec", "enableServiceLinks"))
+
+	pod = testRewriterBeforeServing(t, r, pod)
+	assert.Equal(t, ptr.To(true), pod.Spec.EnableServiceLinks)
+	assert.NotContains(t, pod.GetAnnotations(), annotationForField("spec", "enableServiceLinks"))
+}
+
+func TestSetField_RestoreMissing(t *testing.T) {
+	pod := &corev1.Pod{}
+	r := SetField(int64(10), "spec", "terminationGracePeriodSeconds")
+
+	pod = testRewriterBeforeImport(t, r, pod)
+	assert.Equal(t, ptr.To(int64(10)), pod.Spec.TerminationGracePeriodSeconds)
+
+	pod = testRewriterBeforeServing(t, r, pod)
+	assert.Nil(t, pod.Spec.TerminationGracePeriodSeconds)
+}
+
+func TestRenameAnnotation(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"example.com/original": "value"},
+		},
+	}
+	r := RenameAnnotation("example.com/original", "troubleshoot-live/renamed")
+
+	pod = testRewriterBeforeImport(t, r, pod)
+	assert.Equal(t, map[string]string{"troubleshoot-live/renamed": "value"}, pod.GetAnnotations())
+
+	pod = testRewriterBeforeServing(t, r, pod)
+	assert.Equal(t, map[string]string{"example.com/original": "value"}, pod.GetAnnotations())
+}
@@ -0,0 +1,139 @@
+package rewriter
+
+import (
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ ResourceRewriter = (*timeShift)(nil)
+
+// microTimeFormat is the serialization format of `metav1.MicroTime`.
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// timestampFieldNames lists names of fields that hold timestamps which are
+// shifted. Fields are matched by name at any level of object metadata and
+// status.
+var timestampFieldNames = map[string]bool{
+	"completionTime":     true,
+	"creationTimestamp":  true,
+	"deletionTimestamp":  true,
+	"finishedAt":         true,
+	"lastHeartbeatTime":  true,
+	"lastProbeTime":      true,
+	"lastScheduleTime":   true,
+	"lastSuccessfulTime": true,
+	"lastTransitionTime": true,
+	"lastUpdateTime":     true,
+	"startTime":          true,
+	"startedAt":          true,
+	"time":               true,
+}
+
+// eventTimestampPaths lists timestamps of events, which are stored outside of
+// the event status.
+var eventTimestampPaths = [][]string{
+	{"eventTime"},
+	{"firstTimestamp"},
+	{"lastTimestamp"},
+	{"deprecatedFirstTimestamp"},
+	{"deprecatedLastTimestamp"},
+	{"series", "lastObservedTime"},
+}
+
+// TimeShift moves timestamps of served objects by given offset. This can be
+// used for presenting the cluster as of the time when the bundle was collected,
+// so that relative ages computed by clients against the current time match
+// the ages seen in the original cluster. BeforeImport reverts the shift so that
+// objects written back through the proxy are stored with original timestamps.
+func TimeShift(offset time.Duration) ResourceRewriter {
+	return &timeShift{offset: offset}
+}
+
+type timeShift struct {
+	offset time.Duration
+}
+
+func (r *timeShift) BeforeImport(u *unstructured.Unstructured) error {
+	shiftObjectTimestamps(u.Object, -r.offset)
+	return nil
+}
+
+func (r *timeShift) BeforeServing(u *unstructured.Unstructured) error {
+	shiftObjectTimestamps(u.Object, r.offset)
+	return nil
+}
+
+// shiftObjectTimestamps shifts timestamps of object metadata and status, and
+// timestamps of events. Spec and other fields may contain user provided values
+// which are not shifted.
+func shiftObjectTimestamps(object map[string]any, offset time.Duration) {
+	if offset == 0 {
+		return
+	}
+
+	if metadata, ok := object["metadata"].(map[string]any); ok {
+		shiftMetadataTimestamps(metadata, offset)
+	}
+	if status, ok := object["status"].(map[string]any); ok {
+		object["status"] = shiftTimestamps("status", status, offset)
+	}
+
+	if kind, _ := object["kind"].(string); kind != "Event" {
+		return
+	}
+	for _, path := range eventTimestampPaths {
+		value, ok, err := unstructured.NestedString(object, path...)
+		if err != nil || !ok {
+			continue
+		}
+		_ = unstructured.SetNestedField(object, shiftTimestamp(value, offset), path...)
+	}
+}
+
+// shiftMetadataTimestamps shifts metadata timestamps and skips labels and
+// annotations which keys are not related to the object schema.
+func shiftMetadataTimestamps(metadata map[string]any, offset time.Duration) {
+	for key, value := range metadata {
+		if key == "labels" || key == "annotations" {
+			continue
+		}
+		metadata[key] = shiftTimestamps(key, value, offset)
+	}
+}
+
+func shiftTimestamps(key string, value any, offset time.Duration) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, nested := range v {
+			v[k] = shiftTimestamps(k, nested, offset)
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = shiftTimestamps("", v[i], offset)
+		}
+		return v
+	case string:
+		if !timestampFieldNames[key] {
+			return v
+		}
+		return shiftTimestamp(v, offset)
+	default:
+		return v
+	}
+}
+
+func shiftTimestamp(value string, offset time.Duration) string {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return value
+	}
+
+	format := time.RFC3339
+	if strings.Contains(value, ".") {
+		format = microTimeFormat
+	}
+	return t.Add(offset).UTC().Format(format)
+}
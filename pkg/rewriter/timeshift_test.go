@@ -0,0 +1,107 @@
+package rewriter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestTimeShift(t *testing.T) {
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	transition := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
+	offset := 21 * 24 * time.Hour
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "pod",
+			CreationTimestamp: metav1.NewTime(created),
+			Annotations: map[string]string{
+				"time": "2023-01-01T00:00:00Z",
+			},
+		},
+		Status: corev1.PodStatus{
+			StartTime: &metav1.Time{Time: created},
+			Conditions: []corev1.PodCondition{
+				{
+					Type:               corev1.PodReady,
+					LastTransitionTime: metav1.NewTime(transition),
+				},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "app",
+					State: corev1.ContainerState{
+						Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(transition)},
+					},
+				},
+			},
+		},
+	}
+
+	r := TimeShift(offset)
+	pod = testRewriterBeforeServing(t, r, pod)
+	assert.Equal(t, created.Add(offset), pod.CreationTimestamp.UTC())
+	assert.Equal(t, created.Add(offset), pod.Status.StartTime.UTC())
+	assert.Equal(t, transition.Add(offset), pod.Status.Conditions[0].LastTransitionTime.UTC())
+	assert.Equal(t, transition.Add(offset), pod.Status.ContainerStatuses[0].State.Running.StartedAt.UTC())
+	assert.Equal(t, "2023-01-01T00:00:00Z", pod.Annotations["time"])
+
+	pod = testRewriterBeforeImport(t, r, pod)
+	assert.Equal(t, created, pod.CreationTimestamp.UTC())
+	assert.Equal(t, created, pod.Status.StartTime.UTC())
+	assert.Equal(t, transition, pod.Status.Conditions[0].LastTransitionTime.UTC())
+	assert.Equal(t, transition, pod.Status.ContainerStatuses[0].State.Running.StartedAt.UTC())
+}
+
+func TestTimeShift_PreservesMicroTimeAndShiftsEvents(t *testing.T) {
+	u := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "events.k8s.io/v1",
+			"kind":       "Event",
+			"metadata": map[string]any{
+				"name": "event",
+			},
+			"eventTime": "2023-01-01T00:00:00.123456Z",
+			"series": map[string]any{
+				"lastObservedTime": "2023-01-01T00:00:00.123456Z",
+			},
+		},
+	}
+
+	require.NoError(t, TimeShift(time.Hour).BeforeServing(u))
+	assert.Equal(t, "2023-01-01T01:00:00.123456Z", u.Object["eventTime"])
+	assert.Equal(t, "2023-01-01T01:00:00.123456Z", u.Object["series"].(map[string]any)["lastObservedTime"])
+}
+
+func TestTimeShift_SkipsFieldsOutsideMetadataAndStatus(t *testing.T) {
+	u := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "example.com/v1",
+			"kind":       "Widget",
+			"metadata": map[string]any{
+				"name": "widget",
+			},
+			"spec": map[string]any{
+				"startTime": "2023-01-01T00:00:00Z",
+			},
+			"data": map[string]any{
+				"startTime": "2023-01-01T00:00:00Z",
+			},
+			"eventTime": "2023-01-01T00:00:00Z",
+			"status": map[string]any{
+				"startTime": "2023-01-01T00:00:00Z",
+			},
+		},
+	}
+
+	require.NoError(t, TimeShift(time.Hour).BeforeServing(u))
+	assert.Equal(t, "2023-01-01T00:00:00Z", u.Object["spec"].(map[string]any)["startTime"])
+	assert.Equal(t, "2023-01-01T00:00:00Z", u.Object["data"].(map[string]any)["startTime"])
+	assert.Equal(t, "2023-01-01T00:00:00Z", u.Object["eventTime"])
+	assert.Equal(t, "2023-01-01T01:00:00Z", u.Object["status"].(map[string]any)["startTime"])
+}
@@ -0,0 +1,103 @@
+package snapshot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/envtest"
+)
+
+// FormatVersion is the version of the snapshot file format.
+const FormatVersion = 1
+
+// Metadata describes API server state stored in a snapshot.
+type Metadata struct {
+	FormatVersion int `json:"formatVersion"`
+
+	// KubernetesVersion is the version of the API server which stored the
+	// data. The snapshot must be served by the same minor version.
+	KubernetesVersion string `json:"kubernetesVersion"`
+
+	// BundleHash is the content hash of the imported bundle.
+	BundleHash string `json:"bundleHash,omitempty"`
+
+	// RewriterRules are the additional rewriter rules in YAML format used for
+	// the import. Objects are stored with annotations of the rewriters and the
+	// same rules are needed to restore the objects when they are served.
+	RewriterRules string `json:"rewriterRules,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Write writes snapshot of the data stored for the storage allocation. The
+// snapshot is a gzip compressed stream of JSON encoded metadata on the first
+// line followed by the storage backend snapshot data.
+func Write(
+	ctx context.Context,
+	w io.Writer,
+	meta Metadata,
+	storageBackend envtest.StorageBackend,
+	storageID string,
+) error {
+	meta.FormatVersion = FormatVersion
+
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(meta); err != nil {
+		return fmt.Errorf("failed to write snapshot metadata: %w", err)
+	}
+	if err := storageBackend.Snapshot(ctx, storageID, gz); err != nil {
+		return fmt.Errorf("failed to snapshot storage: %w", err)
+	}
+	return gz.Close()
+}
+
+// Snapshot is a snapshot read by Read.
+type Snapshot struct {
+	Metadata Metadata
+
+	data io.Reader
+}
+
+// Read reads snapshot metadata. The stored data are read by Restore.
+func Read(r io.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	data := bufio.NewReader(gz)
+	line, err := data.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot metadata: %w", err)
+	}
+
+	meta := Metadata{}
+	if err := json.Unmarshal(line, &meta); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot metadata: %w", err)
+	}
+	if meta.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot format version %d", meta.FormatVersion)
+	}
+
+	return &Snapshot{Metadata: meta, data: data}, nil
+}
+
+// Restore replaces data stored for the storage allocation with the snapshot
+// data. The snapshot can be restored only once.
+func (s *Snapshot) Restore(ctx context.Context, storageBackend envtest.StorageBackend, storageID string) error {
+	if s.data == nil {
+		return fmt.Errorf("snapshot was already restored")
+	}
+	data := s.data
+	s.data = nil
+
+	if err := storageBackend.Restore(ctx, storageID, data); err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	return nil
+}
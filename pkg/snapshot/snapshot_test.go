@@ -0,0 +1,79 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mhrabovcin/troubleshoot-live/pkg/envtest"
+)
+
+// memoryStorageBackend keeps snapshot data of each storage allocation.
+type memoryStorageBackend struct {
+	data map[string][]byte
+}
+
+var _ envtest.StorageBackend = &memoryStorageBackend{}
+
+func (m *memoryStorageBackend) Start(_ context.Context) error {
+	return nil
+}
+
+func (m *memoryStorageBackend) Allocate(_ context.Context, _ string) (*envtest.StorageAllocation, error) {
+	return &envtest.StorageAllocation{}, nil
+}
+
+func (m *memoryStorageBackend) Delete(_ context.Context, id string) error {
+	delete(m.data, id)
+	return nil
+}
+
+func (m *memoryStorageBackend) Snapshot(_ context.Context, id string, w io.Writer) error {
+	_, err := w.Write(m.data[id])
+	return err
+}
+
+func (m *memoryStorageBackend) Restore(_ context.Context, id string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	m.data[id] = data
+	return err
+}
+
+func (m *memoryStorageBackend) Stop() error {
+	return nil
+}
+
+func TestWriteAndRestore(t *testing.T) {
+	storage := &memoryStorageBackend{data: map[string][]byte{
+		"first": []byte("{\"key\":\"a\"}\n{\"key\":\"b\"}\n"),
+	}}
+	meta := Metadata{
+		KubernetesVersion: "v1.29.3",
+		BundleHash:        "abc",
+		RewriterRules:     "rules: []\n",
+		CreatedAt:         time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Write(context.Background(), buf, meta, storage, "first"))
+
+	s, err := Read(buf)
+	require.NoError(t, err)
+	meta.FormatVersion = FormatVersion
+	assert.Equal(t, meta, s.Metadata)
+
+	require.NoError(t, s.Restore(context.Background(), storage, "second"))
+	assert.Equal(t, storage.data["first"], storage.data["second"])
+
+	assert.Error(t, s.Restore(context.Background(), storage, "third"))
+}
+
+func TestReadRejectsInvalidSnapshot(t *testing.T) {
+	_, err := Read(bytes.NewBufferString("not a snapshot"))
+	assert.Error(t, err)
+}
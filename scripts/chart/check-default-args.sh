@@ -0,0 +1,14 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Renders the chart with the default values and checks that the flags enabled
+# by default are passed to the serve command.
+chart_dir="$(dirname "${BASH_SOURCE[0]}")/../../charts/troubleshoot-live"
+rendered="$(helm template troubleshoot-live "${chart_dir}")"
+
+for arg in '- "serve"' '- "--read-only"'; do
+  if ! grep -qF -- "${arg}" <<<"${rendered}"; then
+    echo "Chart rendered with default values is missing container arg: ${arg}"
+    exit 1
+  fi
+done